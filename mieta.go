@@ -1,15 +1,22 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	_ "github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
+	"github.com/tokuhirom/mieta/mieta/bookmark"
+	"github.com/tokuhirom/mieta/mieta/bookmark_view"
 	"github.com/tokuhirom/mieta/mieta/config"
 	"github.com/tokuhirom/mieta/mieta/files_view"
 	"github.com/tokuhirom/mieta/mieta/help_view"
+	"github.com/tokuhirom/mieta/mieta/history_view"
 	"github.com/tokuhirom/mieta/mieta/search_view"
+	"github.com/tokuhirom/mieta/mieta/session"
 	"io"
 	"log"
 	"os"
+	"path/filepath"
 )
 
 func main() {
@@ -30,28 +37,50 @@ func main() {
 		log.SetOutput(io.Discard)
 	}
 
+	revision := flag.String("rev", "", "browse the tree at the given git revision instead of the working tree")
+	flag.Parse()
+
 	// コマンドライン引数でディレクトリを指定
 	var rootDir string
-	if len(os.Args) > 1 {
-		rootDir = os.Args[1]
+	if flag.NArg() > 0 {
+		rootDir = flag.Arg(0)
 	} else {
 		rootDir, _ = os.Getwd()
 	}
+	if absRootDir, err := filepath.Abs(rootDir); err == nil {
+		rootDir = absRootDir
+	}
 
 	config := config.LoadConfig()
-	run(rootDir, config)
+	run(rootDir, *revision, config)
 }
 
-func run(rootDir string, config *config.Config) {
+func run(rootDir string, revision string, config *config.Config) {
 	app := tview.NewApplication()
 
 	pages := tview.NewPages()
 	helpView := help_view.NewHelpView(pages, config)
-	mainView := files_view.NewFilesView(rootDir, config, app, pages)
+	mainView, err := files_view.NewFilesView(rootDir, revision, config, app, pages)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mieta: %v\n", err)
+		os.Exit(1)
+	}
 	pages.AddPage("files", mainView.Flex, true, true)
 	pages.AddPage("help", helpView.Flex, true, false)
 	searchView := search_view.NewSearchView(app, config, mainView, pages, rootDir)
 	pages.AddPage("search", searchView.Flex, true, false)
+	historyView := history_view.NewHistoryView(app, config, pages)
+	pages.AddPage("history", historyView.Flex, true, false)
+	mainView.OpenHistory = historyView.Open
+	bookmarkView := bookmark_view.NewBookmarkView(app, config, pages)
+	pages.AddPage("bookmark", bookmarkView.Flex, true, false)
+	mainView.OpenBookmarks = bookmarkView.Open
+	bookmarkView.OnSelect = func(b bookmark.Bookmark) {
+		mainView.ShowPath(b.Path, b.IsDir)
+	}
+	mainView.OnRootChanged = func(rootDir string) {
+		searchView.RootDir = rootDir
+	}
 
 	//pages.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 	//	switch event.Key() {
@@ -70,13 +99,36 @@ func run(rootDir string, config *config.Config) {
 				app.SetFocus(mainView.TreeView)
 			} else if name == "search" {
 				app.SetFocus(searchView.InputField)
+			} else if name == "history" {
+				app.SetFocus(historyView.CommitList)
+			} else if name == "bookmark" {
+				app.SetFocus(bookmarkView.BookmarkList)
 			} else if name == "help" {
 				app.SetFocus(helpView.CloseButton)
 			}
 		}
 	})
 
+	// リビジョンの表示中はワーキングツリーのセッションを上書きしないように保存も復元もしない
+	if revision == "" {
+		if s, err := session.Load(rootDir); err != nil {
+			log.Printf("Failed to load session: %v", err)
+		} else if s != nil {
+			mainView.RestoreSession(s)
+			searchView.RestoreSession(s)
+		}
+	}
+
 	if err := app.SetRoot(pages, true).SetFocus(mainView.TreeView).Run(); err != nil {
 		panic(err)
 	}
+
+	if revision == "" {
+		s := &session.Session{}
+		mainView.SaveSession(s)
+		searchView.SaveSession(s)
+		if err := session.Save(mainView.RootDir, s); err != nil {
+			log.Printf("Failed to save session: %v", err)
+		}
+	}
 }
@@ -0,0 +1,71 @@
+package bookmark
+
+import (
+	"github.com/tokuhirom/mieta/mieta/state"
+	"sort"
+)
+
+// fileName はブックマークを保存するファイルの名前
+const fileName = "bookmarks.json"
+
+// Bookmark はブックマークしたファイルまたはディレクトリ
+type Bookmark struct {
+	// ブックマークの名前。1 文字の名前はブックマークのページでショートカットキーになる
+	Name  string `json:"name"`
+	Path  string `json:"path"`
+	IsDir bool   `json:"is_dir"`
+}
+
+// Shortcut はショートカットキーを返す。名前が 1 文字でなければ 0 を返す
+func (b *Bookmark) Shortcut() rune {
+	runes := []rune(b.Name)
+	if len(runes) == 1 {
+		return runes[0]
+	}
+	return 0
+}
+
+// Load は保存されているブックマークを名前順に返す
+func Load() ([]Bookmark, error) {
+	var bookmarks []Bookmark
+	if err := state.Load(fileName, &bookmarks); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(bookmarks, func(i, j int) bool {
+		return bookmarks[i].Name < bookmarks[j].Name
+	})
+	return bookmarks, nil
+}
+
+// Add はブックマークを追加する。同じ名前のブックマークがあれば置き換える
+func Add(bookmark Bookmark) error {
+	bookmarks, err := Load()
+	if err != nil {
+		return err
+	}
+
+	bookmarks = removeByName(bookmarks, bookmark.Name)
+	bookmarks = append(bookmarks, bookmark)
+	return state.Save(fileName, bookmarks)
+}
+
+// Remove は name のブックマークを削除する
+func Remove(name string) error {
+	bookmarks, err := Load()
+	if err != nil {
+		return err
+	}
+
+	return state.Save(fileName, removeByName(bookmarks, name))
+}
+
+func removeByName(bookmarks []Bookmark, name string) []Bookmark {
+	result := bookmarks[:0]
+	for _, b := range bookmarks {
+		if b.Name != name {
+			result = append(result, b)
+		}
+	}
+	return result
+}
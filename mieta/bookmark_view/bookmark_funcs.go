@@ -0,0 +1,32 @@
+package bookmark_view
+
+// BookmarkExitView はブックマークのページを閉じます
+func BookmarkExitView(view *BookmarkView) {
+	view.Pages.HidePage("bookmark")
+}
+
+// BookmarkPreviousItem は前のブックマークを選択します
+func BookmarkPreviousItem(view *BookmarkView) {
+	index := view.BookmarkList.GetCurrentItem()
+	if index > 0 {
+		view.BookmarkList.SetCurrentItem(index - 1)
+	}
+}
+
+// BookmarkNextItem は次のブックマークを選択します
+func BookmarkNextItem(view *BookmarkView) {
+	index := view.BookmarkList.GetCurrentItem()
+	if index < view.BookmarkList.GetItemCount()-1 {
+		view.BookmarkList.SetCurrentItem(index + 1)
+	}
+}
+
+// BookmarkJump は選択中のブックマークにジャンプします
+func BookmarkJump(view *BookmarkView) {
+	view.Jump(view.BookmarkList.GetCurrentItem())
+}
+
+// BookmarkDelete は選択中のブックマークを削除します
+func BookmarkDelete(view *BookmarkView) {
+	view.Delete(view.BookmarkList.GetCurrentItem())
+}
@@ -0,0 +1,30 @@
+package bookmark_view
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/tokuhirom/mieta/mieta/config"
+	"github.com/tokuhirom/mieta/mieta/keymap"
+)
+
+type BookmarkViewHandler func(view *BookmarkView)
+
+var BookmarkFunctions = map[string]BookmarkViewHandler{
+	"BookmarkExitView":     BookmarkExitView,
+	"BookmarkPreviousItem": BookmarkPreviousItem,
+	"BookmarkNextItem":     BookmarkNextItem,
+	"BookmarkJump":         BookmarkJump,
+	"BookmarkDelete":       BookmarkDelete,
+}
+
+var DefaultKeyMap = map[string]string{
+	"Esc":   "BookmarkExitView",
+	"q":     "BookmarkExitView",
+	"w":     "BookmarkPreviousItem",
+	"s":     "BookmarkNextItem",
+	"Enter": "BookmarkJump",
+	"X":     "BookmarkDelete",
+}
+
+func GetBookmarkKeymap(config *config.Config) (map[string]string, map[tcell.Key]BookmarkViewHandler, map[rune]BookmarkViewHandler) {
+	return keymap.ProcessKeymap("bookmark", DefaultKeyMap, config.BookmarkKeyMap, BookmarkFunctions)
+}
@@ -0,0 +1,140 @@
+package bookmark_view
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/tokuhirom/mieta/mieta/bookmark"
+	"github.com/tokuhirom/mieta/mieta/config"
+	"log"
+)
+
+// BookmarkView はブックマークの一覧を表示して、選んだ場所にジャンプするビュー
+type BookmarkView struct {
+	Application  *tview.Application
+	Config       *config.Config
+	Pages        *tview.Pages
+	Flex         *tview.Flex
+	BookmarkList *tview.List
+	Bookmarks    []bookmark.Bookmark
+	// ブックマークが選ばれたときに呼ばれる関数
+	OnSelect func(b bookmark.Bookmark)
+}
+
+// NewBookmarkView creates a new bookmark view
+func NewBookmarkView(app *tview.Application, config *config.Config, pages *tview.Pages) *BookmarkView {
+	bookmarkList := tview.NewList().
+		ShowSecondaryText(true)
+	bookmarkList.SetBorder(true)
+	bookmarkList.SetBorderColor(tcell.ColorDarkSlateGray)
+	bookmarkList.SetTitle("Bookmarks")
+
+	statusBar := tview.NewTextView().
+		SetDynamicColors(true).
+		SetTextAlign(tview.AlignLeft)
+	statusBar.SetText("[yellow]Enter[white]: Jump | [yellow]X[white]: Delete | [yellow]Esc[white]: Exit Bookmarks")
+
+	flex := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(bookmarkList, 0, 1, true).
+		AddItem(statusBar, 1, 0, false)
+
+	bookmarkView := &BookmarkView{
+		Application:  app,
+		Config:       config,
+		Pages:        pages,
+		Flex:         flex,
+		BookmarkList: bookmarkList,
+	}
+
+	bookmarkList.SetSelectedFunc(func(index int, mainText string, secondaryText string, shortcut rune) {
+		bookmarkView.Jump(index)
+	})
+
+	_, keycodeKeymap, runeKeymap := GetBookmarkKeymap(config)
+	bookmarkList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if handler, ok := keycodeKeymap[event.Key()]; ok {
+			handler(bookmarkView)
+			return nil
+		}
+
+		if event.Key() == tcell.KeyRune {
+			// ブックマークのショートカットキーを優先する
+			if bookmarkView.hasShortcut(event.Rune()) {
+				return event
+			}
+			if handler, ok := runeKeymap[event.Rune()]; ok {
+				handler(bookmarkView)
+				return nil
+			}
+		}
+		return event
+	})
+
+	return bookmarkView
+}
+
+// Open はブックマークを読み込み直してページを表示する
+func (b *BookmarkView) Open() {
+	b.reload()
+	b.Pages.ShowPage("bookmark")
+}
+
+// reload は保存されているブックマークを読み込んで一覧を作り直す
+func (b *BookmarkView) reload() {
+	current := b.BookmarkList.GetCurrentItem()
+	b.BookmarkList.Clear()
+
+	bookmarks, err := bookmark.Load()
+	if err != nil {
+		log.Printf("Failed to load bookmarks: %v", err)
+		b.BookmarkList.SetTitle(fmt.Sprintf("Bookmarks: [red]%s", tview.Escape(err.Error())))
+		return
+	}
+
+	b.Bookmarks = bookmarks
+	for _, bm := range bookmarks {
+		icon := "📄"
+		if bm.IsDir {
+			icon = "📁"
+		}
+		b.BookmarkList.AddItem(icon+tview.Escape(bm.Name), tview.Escape(bm.Path), bm.Shortcut(), nil)
+	}
+	b.BookmarkList.SetTitle(fmt.Sprintf("Bookmarks (%d)", len(bookmarks)))
+	if current < len(bookmarks) {
+		b.BookmarkList.SetCurrentItem(current)
+	}
+}
+
+// Jump は index 番目のブックマークを選んでページを閉じる
+func (b *BookmarkView) Jump(index int) {
+	if index < 0 || index >= len(b.Bookmarks) {
+		return
+	}
+
+	b.Pages.HidePage("bookmark")
+	if b.OnSelect != nil {
+		b.OnSelect(b.Bookmarks[index])
+	}
+}
+
+// Delete は index 番目のブックマークを削除する
+func (b *BookmarkView) Delete(index int) {
+	if index < 0 || index >= len(b.Bookmarks) {
+		return
+	}
+
+	if err := bookmark.Remove(b.Bookmarks[index].Name); err != nil {
+		log.Printf("Failed to remove bookmark: %v", err)
+	}
+	b.reload()
+}
+
+func (b *BookmarkView) hasShortcut(r rune) bool {
+	for _, bm := range b.Bookmarks {
+		if bm.Shortcut() == r {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,256 @@
+package charset
+
+import (
+	"bytes"
+	"fmt"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// 文字コードを判定するために変換してみる最大のバイト数。大きなファイルは先頭だけで判定する
+const maxSampleSize = 64 * 1024
+
+// Charset はテキストの文字コード
+type Charset struct {
+	// 表示用の名前（"UTF-8", "Shift_JIS" など）
+	Name string
+	// 先頭に BOM があるかどうか
+	BOM bool
+	// UTF-8 に変換するためのエンコーディング。UTF-8 と ASCII なら nil
+	encoding encoding.Encoding
+}
+
+var (
+	ascii      = &Charset{Name: "ASCII"}
+	utf8Plain  = &Charset{Name: "UTF-8"}
+	utf8BOM    = &Charset{Name: "UTF-8", BOM: true}
+	utf16LEBOM = &Charset{Name: "UTF-16LE", BOM: true, encoding: unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)}
+	utf16BEBOM = &Charset{Name: "UTF-16BE", BOM: true, encoding: unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)}
+	utf16LE    = &Charset{Name: "UTF-16LE", encoding: unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)}
+	utf16BE    = &Charset{Name: "UTF-16BE", encoding: unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)}
+	shiftJIS   = &Charset{Name: "Shift_JIS", encoding: japanese.ShiftJIS}
+	eucJP      = &Charset{Name: "EUC-JP", encoding: japanese.EUCJP}
+	iso2022JP  = &Charset{Name: "ISO-2022-JP", encoding: japanese.ISO2022JP}
+)
+
+func (c *Charset) String() string {
+	if c.BOM {
+		return c.Name + " (BOM)"
+	}
+	return c.Name
+}
+
+// Decode は content を UTF-8 に変換する。BOM は取り除く
+func (c *Charset) Decode(content []byte) ([]byte, error) {
+	if c.BOM {
+		switch {
+		case bytes.HasPrefix(content, bomUTF8):
+			content = content[len(bomUTF8):]
+		case bytes.HasPrefix(content, bomUTF16LE), bytes.HasPrefix(content, bomUTF16BE):
+			content = content[len(bomUTF16LE):]
+		}
+	}
+	if c.encoding == nil {
+		return content, nil
+	}
+	return c.encoding.NewDecoder().Bytes(content)
+}
+
+// Lookup は "shift_jis", "euc-jp", "utf-16le" などの名前から文字コードを返す
+func Lookup(name string) (*Charset, error) {
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown encoding %q", name)
+	}
+	if enc == encoding.Nop || enc == unicode.UTF8 {
+		return utf8Plain, nil
+	}
+
+	displayName, err := ianaindex.MIME.Name(enc)
+	if err != nil || displayName == "" {
+		displayName = name
+	}
+	return &Charset{Name: displayName, encoding: enc}, nil
+}
+
+// Detect は content の文字コードを判定する。テキストでなければ nil を返す
+// BOM、ISO-2022-JP のエスケープシーケンス、BOM のない UTF-16、UTF-8 の順に調べ、
+// どれでもなければ Shift_JIS と EUC-JP で変換してみて、日本語として自然なほうを選ぶ
+func Detect(content []byte) *Charset {
+	return detect(content, false)
+}
+
+// DetectPrefix はファイルの先頭だけを使って文字コードを判定する
+// 末尾で途中までしか読んでいない文字は無視する
+func DetectPrefix(head []byte) *Charset {
+	return detect(head, true)
+}
+
+func detect(content []byte, truncated bool) *Charset {
+	switch {
+	case bytes.HasPrefix(content, bomUTF8):
+		return utf8BOM
+	case bytes.HasPrefix(content, bomUTF16LE):
+		return utf16LEBOM
+	case bytes.HasPrefix(content, bomUTF16BE):
+		return utf16BEBOM
+	}
+
+	if bytes.IndexByte(content, 0) >= 0 {
+		return detectUTF16(content, truncated)
+	}
+	if isISO2022JP(content) {
+		return iso2022JP
+	}
+
+	if truncated {
+		content = trimPartialRune(content)
+	}
+	if utf8.Valid(content) {
+		for _, b := range content {
+			if b >= utf8.RuneSelf {
+				return utf8Plain
+			}
+		}
+		return ascii
+	}
+
+	// どちらでも変換できる場合は、ひらがなやカタカナが多いほうを選ぶ
+	sample, sampleTruncated := sampleOf(content, truncated)
+	var best *Charset
+	bestScore := 0
+	for _, candidate := range []*Charset{shiftJIS, eucJP} {
+		score, ok := scoreJapanese(candidate, sample, sampleTruncated)
+		if ok && (best == nil || score > bestScore) {
+			best, bestScore = candidate, score
+		}
+	}
+	return best
+}
+
+// detectUTF16 は NUL を含むテキストが BOM のない UTF-16 かを判定する
+// ASCII の文字が多いと、上位か下位のバイトの片方だけに NUL が集まることを使う
+func detectUTF16(content []byte, truncated bool) *Charset {
+	if truncated && len(content)%2 == 1 {
+		content = content[:len(content)-1]
+	}
+	if len(content) < 2 {
+		return nil
+	}
+
+	var evenZeros, oddZeros int
+	for i, b := range content {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			evenZeros++
+		} else {
+			oddZeros++
+		}
+	}
+
+	half := len(content) / 2
+	var candidate *Charset
+	switch {
+	case oddZeros > half/4 && evenZeros == 0:
+		candidate = utf16LE
+	case evenZeros > half/4 && oddZeros == 0:
+		candidate = utf16BE
+	default:
+		return nil
+	}
+	sample, sampleTruncated := sampleOf(content, truncated)
+	if _, ok := decodeClean(candidate, sample, sampleTruncated); !ok {
+		return nil
+	}
+	return candidate
+}
+
+// sampleOf は変換して判定するための content の先頭を返す。途中で切った場合は truncated を true にする
+func sampleOf(content []byte, truncated bool) ([]byte, bool) {
+	if len(content) <= maxSampleSize {
+		return content, truncated
+	}
+	return content[:maxSampleSize], true
+}
+
+// isISO2022JP は 7 ビットのテキストに ISO-2022-JP のエスケープシーケンスが含まれるかを返す
+func isISO2022JP(content []byte) bool {
+	for _, b := range content {
+		if b >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return bytes.Contains(content, []byte("\x1b$B")) ||
+		bytes.Contains(content, []byte("\x1b$@")) ||
+		bytes.Contains(content, []byte("\x1b(J"))
+}
+
+// scoreJapanese は content を charset で変換して、日本語としての自然さを点数にする
+// 変換できない場合やテキストとして不自然な制御文字が含まれる場合は ok が false になる
+func scoreJapanese(charset *Charset, content []byte, truncated bool) (score int, ok bool) {
+	decoded, ok := decodeClean(charset, content, truncated)
+	if !ok {
+		return 0, false
+	}
+
+	for _, r := range decoded {
+		switch {
+		case r >= 0x3040 && r <= 0x30FF:
+			// ひらがなとカタカナ
+			score += 2
+		case r >= 0x4E00 && r <= 0x9FFF:
+			// 漢字
+			score++
+		case r >= 0xFF61 && r <= 0xFF9F:
+			// 半角カタカナは EUC-JP の 2 バイト文字が誤って解釈されたときにも出やすい
+			score--
+		}
+	}
+	return score, true
+}
+
+// decodeClean は content を charset で変換する。変換できない文字や制御文字が含まれていれば ok が false になる
+// truncated なら末尾の変換できない文字は途中で切れたものとして無視する
+func decodeClean(charset *Charset, content []byte, truncated bool) (string, bool) {
+	decoded, err := charset.Decode(content)
+	if err != nil {
+		return "", false
+	}
+
+	text := string(decoded)
+	if truncated {
+		text = strings.TrimSuffix(text, string(utf8.RuneError))
+	}
+	for _, r := range text {
+		if r == utf8.RuneError || (r < 0x20 && r != '\t' && r != '\n' && r != '\r' && r != '\f' && r != 0x1b) {
+			return "", false
+		}
+	}
+	return text, true
+}
+
+// trimPartialRune は末尾で途中までしかない UTF-8 の文字を取り除く
+func trimPartialRune(content []byte) []byte {
+	for i := 1; i <= utf8.UTFMax && i <= len(content); i++ {
+		if utf8.RuneStart(content[len(content)-i]) {
+			if !utf8.FullRune(content[len(content)-i:]) {
+				return content[:len(content)-i]
+			}
+			break
+		}
+	}
+	return content
+}
@@ -0,0 +1,99 @@
+package charset
+
+import (
+	"bytes"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+	"strings"
+	"testing"
+)
+
+const japaneseText = "これは日本語のテキストです。カタカナも含みます。\n"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    *Charset
+	}{
+		{"empty", []byte{}, ascii},
+		{"ascii", []byte("hello, world\n"), ascii},
+		{"utf-8", []byte(japaneseText), utf8Plain},
+		{"utf-8 BOM", append(bytes.Clone(bomUTF8), japaneseText...), utf8BOM},
+		{"utf-16le BOM", encode(t, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), japaneseText), utf16LEBOM},
+		{"utf-16be BOM", encode(t, unicode.UTF16(unicode.BigEndian, unicode.UseBOM), japaneseText), utf16BEBOM},
+		// BOM がなくても、ASCII の文字の上位バイトの NUL で判定する
+		{"utf-16le", encode(t, unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), "hello, world\n"), utf16LE},
+		{"utf-16be", encode(t, unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), "hello, world\n"), utf16BE},
+		{"iso-2022-jp", encode(t, japanese.ISO2022JP, japaneseText), iso2022JP},
+		// Shift_JIS と EUC-JP はどちらでも変換できるバイト列があるので、日本語として自然なほうを選ぶ
+		{"shift_jis", encode(t, japanese.ShiftJIS, japaneseText), shiftJIS},
+		{"euc-jp", encode(t, japanese.EUCJP, japaneseText), eucJP},
+		{"shift_jis hiragana only", encode(t, japanese.ShiftJIS, "ひらがなだけのぶんしょう\n"), shiftJIS},
+		{"euc-jp hiragana only", encode(t, japanese.EUCJP, "ひらがなだけのぶんしょう\n"), eucJP},
+		// 大きなファイルは先頭だけで判定する
+		{"large shift_jis", encode(t, japanese.ShiftJIS, strings.Repeat(japaneseText, maxSampleSize/len(japaneseText)*2)), shiftJIS},
+		{"large euc-jp", encode(t, japanese.EUCJP, strings.Repeat(japaneseText, maxSampleSize/len(japaneseText)*2)), eucJP},
+		{"binary", []byte{0x7f, 'E', 'L', 'F', 0x02, 0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x03, 0x00}, nil},
+		{"invalid multibyte", []byte{0x82, 0xa0, 0xff, 0xfe, 0x01, 0x02}, nil},
+	}
+
+	for _, tt := range tests {
+		if got := Detect(tt.content); got != tt.want {
+			t.Errorf("Detect(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDetectPrefix(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    *Charset
+	}{
+		// 途中で切れた最後の文字は無視する
+		{"utf-8", []byte(japaneseText)[:4], utf8Plain},
+		{"shift_jis", encode(t, japanese.ShiftJIS, japaneseText)[:5], shiftJIS},
+		{"utf-16le", encode(t, unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), "hello, world\n")[:9], utf16LE},
+	}
+
+	for _, tt := range tests {
+		if got := DetectPrefix(tt.content); got != tt.want {
+			t.Errorf("DetectPrefix(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		charset *Charset
+		content []byte
+	}{
+		{"utf-8 BOM", utf8BOM, append(bytes.Clone(bomUTF8), japaneseText...)},
+		{"utf-16le BOM", utf16LEBOM, encode(t, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), japaneseText)},
+		{"shift_jis", shiftJIS, encode(t, japanese.ShiftJIS, japaneseText)},
+		{"euc-jp", eucJP, encode(t, japanese.EUCJP, japaneseText)},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.charset.Decode(tt.content)
+		if err != nil {
+			t.Errorf("Decode(%s) failed: %v", tt.name, err)
+			continue
+		}
+		if string(got) != japaneseText {
+			t.Errorf("Decode(%s) = %q, want %q", tt.name, got, japaneseText)
+		}
+	}
+}
+
+func encode(t *testing.T, enc encoding.Encoding, text string) []byte {
+	t.Helper()
+	encoded, err := enc.NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encoded
+}
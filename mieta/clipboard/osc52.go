@@ -0,0 +1,38 @@
+package clipboard
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Copy は OSC 52 エスケープシーケンスでテキストをシステムのクリップボードにコピーする
+// 端末自身がクリップボードに書き込むので、SSH 越しでも外部コマンドなしで動作する
+func Copy(text string) error {
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open terminal: %w", err)
+	}
+	defer tty.Close()
+
+	_, err = tty.WriteString(sequence(text))
+	return err
+}
+
+// sequence は端末に送るエスケープシーケンスを組み立てる
+func sequence(text string) string {
+	osc52 := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\x07"
+
+	switch {
+	case os.Getenv("TMUX") != "":
+		// tmux は set-clipboard が有効ならそのまま受け付ける
+		// そうでない場合に備えてパススルーでも外側の端末に渡す（allow-passthrough が必要）
+		return osc52 + "\x1bPtmux;" + strings.ReplaceAll(osc52, "\x1b", "\x1b\x1b") + "\x1b\\"
+	case strings.HasPrefix(os.Getenv("TERM"), "screen"):
+		// GNU screen は DCS で囲む
+		return "\x1bP" + osc52 + "\x1b\\"
+	default:
+		return osc52
+	}
+}
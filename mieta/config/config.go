@@ -4,7 +4,9 @@ import (
 	"github.com/BurntSushi/toml"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 )
 
 type SearchConfig struct {
@@ -13,6 +15,33 @@ type SearchConfig struct {
 	ExtraOpts []string `toml:"extra_opts"`
 }
 
+type TreeConfig struct {
+	// ツリーの並び順: "name", "natural", "mtime", "size", "extension"
+	Sort string `toml:"sort"`
+	// ディレクトリをファイルより先に表示する
+	DirsFirst bool `toml:"dirs_first"`
+	// 並び順を逆にする
+	Reverse bool `toml:"reverse"`
+	// ドットファイルを表示する
+	ShowHidden bool `toml:"show_hidden"`
+	// ignore されたファイルをグレーで表示する代わりに隠す
+	HideIgnored bool `toml:"hide_ignored"`
+	// ツリーや監視、ファイルの検索から除外するパターン（例: "node_modules", "*.pyc"）
+	Exclude []string `toml:"exclude"`
+	// 再帰的に展開するときの深さ。0 ならすべて展開する
+	ExpandDepth int `toml:"expand_depth"`
+	// ディレクトリへのシンボリックリンクをたどって中身を表示する
+	FollowSymlinks bool `toml:"follow_symlinks"`
+}
+
+// EncodingConfig はファイルのパターンごとに文字コードを指定する設定
+type EncodingConfig struct {
+	// ファイルのパターン。"/" を含むパターンはルートからの相対パスと、含まないパターンはファイル名と比較する
+	Pattern string `toml:"pattern"`
+	// 文字コードの名前（例: "shift_jis", "euc-jp", "utf-16le"）
+	Encoding string `toml:"encoding"`
+}
+
 type Config struct {
 	// シンタックスハイライトのスタイル
 	ChromaStyle string `toml:"chroma_style"`
@@ -26,10 +55,21 @@ type Config struct {
 	// 検索関連の設定
 	Search SearchConfig `toml:"search"`
 
+	// ツリーの表示に関する設定
+	Tree TreeConfig `toml:"tree"`
+
+	// マークしたファイルに対して実行するコマンド（名前 -> コマンドライン）
+	Commands map[string]string `toml:"commands"`
+
+	// 文字コードを自動で判定する代わりに指定するファイルのパターン。最初に一致したものを使う
+	Encodings []EncodingConfig `toml:"encodings"`
+
 	// Keymaps
-	FilesKeyMap  map[string]string `toml:"keymap.files"`
-	HelpKeyMap   map[string]string `toml:"keymap.help"`
-	SearchKeyMap map[string]string `toml:"keymap.search"`
+	FilesKeyMap    map[string]string `toml:"keymap.files"`
+	HelpKeyMap     map[string]string `toml:"keymap.help"`
+	SearchKeyMap   map[string]string `toml:"keymap.search"`
+	HistoryKeyMap  map[string]string `toml:"keymap.history"`
+	BookmarkKeyMap map[string]string `toml:"keymap.bookmark"`
 }
 
 // LoadConfig は設定ファイルを読み込みます
@@ -40,6 +80,9 @@ func LoadConfig() *Config {
 	config.ChromaStyle = "monokai"
 	config.HighlightLimit = 1000000
 	config.Search.Driver = "ag"
+	config.Tree.Sort = "name"
+	config.Tree.ShowHidden = true
+	config.Tree.ExpandDepth = 3
 
 	// ユーザーホームディレクトリの設定ファイルを試す
 	homeDir, err := os.UserHomeDir()
@@ -67,6 +110,25 @@ chroma_style = "monokai"
 # ハイライト処理を行うファイルサイズの上限（バイト）
 highlight_limit = 1000000
 
+# ツリーの表示に関する設定
+[tree]
+# 並び順: "name", "natural", "mtime", "size", "extension"
+sort = "name"
+# ディレクトリをファイルより先に表示する
+dirs_first = false
+# 並び順を逆にする
+reverse = false
+# ドットファイルを表示する
+show_hidden = true
+# ignore されたファイルをグレーで表示する代わりに隠す
+hide_ignored = false
+# 表示しないファイルのパターン。"/" を含むパターンはルートからの相対パスと比較する
+exclude = []
+# * で再帰的に展開するときの深さ。0 ならすべて展開する
+expand_depth = 3
+# ディレクトリへのシンボリックリンクをたどって中身を表示する。ループになるリンクはたどらない
+follow_symlinks = false
+
 # 検索関連の設定
 [search]
 # 使用する検索ドライバー: "ag" または "rg"
@@ -81,6 +143,17 @@ extra_opts = []
 [search.rg]
 # 追加のコマンドラインオプション
 extra_opts = []
+
+# 文字コードの判定がうまくいかないファイルに、文字コードを指定する。最初に一致したものを使う
+# "/" を含むパターンはルートからの相対パスと、含まないパターンはファイル名と比較する
+# [[encodings]]
+# pattern = "legacy/*.txt"
+# encoding = "shift_jis"
+
+# マークしたファイルに対して ! で実行するコマンド
+# {files} はクォートしたパスに置き換えられます。{files} がなければ末尾にパスを追加します
+[commands]
+# "tar" = "tar czf /tmp/archive.tar.gz {files}"
 `
 				if err := os.WriteFile(configPath, []byte(defaultConfig), 0644); err != nil {
 					log.Printf("デフォルト設定ファイルの作成に失敗しました: %v", err)
@@ -111,6 +184,22 @@ extra_opts = []
 	return config
 }
 
+// EncodingFor は relPath に指定された文字コードの名前を返す。指定がなければ空文字列を返す
+// relPath はルートディレクトリからの "/" 区切りの相対パス
+func (c *Config) EncodingFor(relPath string) string {
+	name := path.Base(relPath)
+	for _, e := range c.Encodings {
+		target := name
+		if strings.Contains(e.Pattern, "/") {
+			target = relPath
+		}
+		if matched, _ := path.Match(e.Pattern, target); matched {
+			return e.Encoding
+		}
+	}
+	return ""
+}
+
 // GetSearchDriver は設定に基づいて適切な検索ドライバーを返します
 func (c *Config) GetSearchDriver() (string, []string) {
 	driver := c.Search.Driver
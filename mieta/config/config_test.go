@@ -0,0 +1,43 @@
+package config
+
+import "testing"
+
+func TestEncodingFor(t *testing.T) {
+	config := &Config{
+		Encodings: []EncodingConfig{
+			{Pattern: "legacy/*.txt", Encoding: "euc-jp"},
+			{Pattern: "*.txt", Encoding: "shift_jis"},
+			{Pattern: "*.csv", Encoding: "utf-16le"},
+			{Pattern: "data/*/*.log", Encoding: "euc-jp"},
+		},
+	}
+
+	tests := []struct {
+		relPath string
+		want    string
+	}{
+		// "/" を含まないパターンはファイル名と比較する
+		{"memo.txt", "shift_jis"},
+		{"docs/deep/memo.txt", "shift_jis"},
+		{"report.csv", "utf-16le"},
+		// "/" を含むパターンはルートからの相対パスと比較する
+		{"legacy/memo.txt", "euc-jp"},
+		{"src/legacy/memo.txt", "shift_jis"},
+		{"data/2024/app.log", "euc-jp"},
+		{"data/app.log", ""},
+		{"app.log", ""},
+		// 最初に一致したものを使う
+		{"legacy/readme.txt", "euc-jp"},
+		{"main.go", ""},
+	}
+
+	for _, tt := range tests {
+		if got := config.EncodingFor(tt.relPath); got != tt.want {
+			t.Errorf("EncodingFor(%q) = %q, want %q", tt.relPath, got, tt.want)
+		}
+	}
+
+	if got := (&Config{}).EncodingFor("memo.txt"); got != "" {
+		t.Errorf("EncodingFor without encodings = %q, want empty", got)
+	}
+}
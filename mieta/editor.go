@@ -1,8 +1,10 @@
 package mieta
 
 import (
+	"bufio"
 	"fmt"
 	"github.com/rivo/tview"
+	"github.com/rivo/uniseg"
 	"github.com/tokuhirom/mieta/mieta/config"
 	"log"
 	"os"
@@ -64,17 +66,126 @@ func GetCurrentLineNumber(textView *tview.TextView) int {
 	_, _, _, height := textView.GetRect()
 
 	// 中央の行を計算（表示領域の中央に表示されている行）
-	centerLine := row + height/2
+	// 折り返した行も 1 行と数えられているので、元のテキストの行に直す
+	centerLine := sourceLineAt(textView, row+height/2)
 
-	// 行数が少ない場合は調整
-	totalLines := textView.GetOriginalLineCount()
-	if centerLine >= totalLines {
-		centerLine = totalLines - 1
+	// 1-indexed に変換（エディタは通常1行目から始まる）
+	return centerLine + 1
+}
+
+// GetVisibleLineRange returns the first and last line shown in the text view (1-indexed)
+// Wrapped rows are mapped back to the lines of the original text
+func GetVisibleLineRange(textView *tview.TextView) (int, int) {
+	if textView.GetOriginalLineCount() == 0 {
+		return 0, 0
 	}
-	if centerLine < 0 {
-		centerLine = 0
+
+	row, _ := textView.GetScrollOffset()
+	_, _, _, height := textView.GetInnerRect()
+	start := sourceLineAt(textView, row)
+	end := sourceLineAt(textView, row+max(height, 1)-1)
+
+	return start + 1, end + 1
+}
+
+// sourceLineAt は表示上の行 row（折り返した行も数える。0 始まり）にある、元のテキストの行（0 始まり）を返す
+// row が最後の行より後ろなら最後の行を返す
+func sourceLineAt(textView *tview.TextView, row int) int {
+	_, _, width, _ := textView.GetInnerRect()
+	lines := strings.Split(textView.GetText(true), "\n")
+
+	displayRow := 0
+	for i, line := range lines {
+		displayRow += wrappedRows(line, width)
+		if row < displayRow {
+			return i
+		}
 	}
+	return len(lines) - 1
+}
 
-	// 1-indexed に変換（エディタは通常1行目から始まる）
-	return centerLine + 1
+// wrappedRows は line を幅 width で折り返したときの行数を返す
+// tview の TextView と同じく、単語の区切りで折り返し、区切りがなければ文字の途中で折り返す
+func wrappedRows(line string, width int) int {
+	if width <= 0 {
+		return 1
+	}
+
+	rows := 1
+	// 今の行の幅、最後に折り返せる位置までの幅、タブの位置を決めるための幅
+	lineWidth, breakWidth, tabPos := 0, 0, 0
+	state := -1
+	for len(line) > 0 {
+		var cluster string
+		var boundaries int
+		cluster, line, boundaries, state = uniseg.StepString(line, state)
+		w := boundaries >> uniseg.ShiftWidth
+		if cluster == "\t" {
+			w = tview.TabSize - tabPos%tview.TabSize
+		}
+
+		if lineWidth+w > width {
+			rows++
+			if breakWidth == 0 {
+				lineWidth, tabPos = 0, 0
+			} else {
+				lineWidth -= breakWidth
+			}
+			breakWidth = 0
+		}
+
+		lineWidth += w
+		tabPos += w
+		if boundaries&uniseg.MaskLine == uniseg.LineCanBreak {
+			breakWidth = lineWidth
+		}
+	}
+	return rows
+}
+
+// OpenFilesInEditor opens multiple files in an external editor at once
+func OpenFilesInEditor(app *tview.Application, config *config.Config, filePaths []string) {
+	editorCmd := config.Editor
+	if editorCmd == "" {
+		log.Fatalf("No editor configured")
+		return
+	}
+
+	app.Suspend(func() {
+		cmd := exec.Command(editorCmd, filePaths...)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+
+		log.Printf("Opening files in editor: %s %v", editorCmd, cmd.Args)
+		if err := cmd.Run(); err != nil {
+			log.Printf("Error opening editor: %v", err)
+		}
+	})
+}
+
+// RunShellCommand runs a command line through the shell with the terminal attached,
+// and waits for Enter before returning to the application
+func RunShellCommand(app *tview.Application, dir string, commandLine string) {
+	app.Suspend(func() {
+		cmd := exec.Command("sh", "-c", commandLine)
+		cmd.Dir = dir
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+
+		log.Printf("Running command: %s", commandLine)
+		if err := cmd.Run(); err != nil {
+			log.Printf("Error running command: %v", err)
+			fmt.Printf("\n%v\n", err)
+		}
+
+		fmt.Print("\nPress Enter to return to mieta...")
+		_, _ = bufio.NewReader(os.Stdin).ReadString('\n')
+	})
+}
+
+// ShellQuote quotes a string for use as a single shell word
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
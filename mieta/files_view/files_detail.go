@@ -0,0 +1,103 @@
+package files_view
+
+import (
+	"fmt"
+	"github.com/rivo/tview"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultTreeWidth は左ペインの最初の幅
+const defaultTreeWidth = 30
+
+// setTreeWidth は左ペインの幅を変更する。詳細表示中は列の幅も合わせて直す
+func (m *FilesView) setTreeWidth(width int) {
+	m.treeWidth = width
+	m.Flex.ResizeItem(m.LeftPane, width, 1)
+	if m.DetailMode {
+		m.refreshNodeStyles(m.TreeView.GetRoot())
+	}
+}
+
+// toggleDetailMode はサイズや更新日時などの列を表示するかを切り替える
+func (m *FilesView) toggleDetailMode() {
+	m.DetailMode = !m.DetailMode
+	m.refreshNodeStyles(m.TreeView.GetRoot())
+}
+
+// nodeLevel はツリーでのノードの深さを返す。ルートが 0
+func (m *FilesView) nodeLevel(path string) int {
+	relPath := m.relativePath(path)
+	if relPath == "." {
+		return 0
+	}
+	return strings.Count(relPath, string(filepath.Separator)) + 1
+}
+
+// appendDetailColumns は label の右側にサイズ、更新日時、パーミッションの列を右寄せで追加する
+// 入りきらない列は優先度の低いもの（パーミッション、更新日時の順）から省く
+func (m *FilesView) appendDetailColumns(label string, fileNode *FileNode) string {
+	// ツリーの枠線と、深さに応じたインデント（罫線 1 文字 + インデント 2 文字）を除いた幅
+	available := m.treeWidth - 2 - m.nodeLevel(fileNode.Path)*3 - tview.TaggedStringWidth(label)
+
+	columns := detailColumns(fileNode)
+	for len(columns) > 0 {
+		text := " " + strings.Join(columns, " ")
+		// ラベルと同じく、バイト数ではなく表示幅で揃える
+		width := tview.TaggedStringWidth(tview.Escape(text))
+		if width <= available {
+			return label + strings.Repeat(" ", available-width) + "[gray]" + tview.Escape(text) + "[-]"
+		}
+		columns = columns[:len(columns)-1]
+	}
+	return label
+}
+
+// detailColumns は優先度の高い順に列の文字列を返す
+func detailColumns(fileNode *FileNode) []string {
+	if fileNode.info == nil {
+		return nil
+	}
+
+	size := fmt.Sprintf("%5s", "-")
+	if !fileNode.IsDir {
+		size = fmt.Sprintf("%5s", humanSize(fileNode.info.Size()))
+	}
+	return []string{
+		size,
+		formatModTime(fileNode.info.ModTime()),
+		fileNode.info.Mode().String(),
+	}
+}
+
+// humanSize はサイズを 4 文字以内の読みやすい形式にする
+func humanSize(size int64) string {
+	const units = "KMGTPE"
+	if size < 1000 {
+		return fmt.Sprintf("%dB", size)
+	}
+
+	value := float64(size)
+	for _, unit := range units {
+		value /= 1024
+		if value < 9.95 {
+			return fmt.Sprintf("%.1f%c", value, unit)
+		}
+		if value < 999.5 {
+			return fmt.Sprintf("%.0f%c", value, unit)
+		}
+	}
+	return fmt.Sprintf("%.0fE", value)
+}
+
+// formatModTime は ls と同じように、今年の日時は時刻を、それ以外は年を表示する
+func formatModTime(modTime time.Time) string {
+	if modTime.IsZero() {
+		return fmt.Sprintf("%12s", "-")
+	}
+	if modTime.Year() == time.Now().Year() {
+		return modTime.Format("Jan _2 15:04")
+	}
+	return modTime.Format("Jan _2  2006")
+}
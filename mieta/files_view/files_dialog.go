@@ -0,0 +1,80 @@
+package files_view
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// dialogPage はダイアログを表示するページの名前
+const dialogPage = "dialog"
+
+// showDialog は primitive を画面中央に表示する
+func (m *FilesView) showDialog(primitive tview.Primitive, width int, height int) {
+	// modal は中央揃えのテキストしか表示できないので、入力欄などは flex で中央に配置する
+	flex := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(primitive, height, 1, true).
+			AddItem(nil, 0, 1, false), width, 1, true).
+		AddItem(nil, 0, 1, false)
+
+	m.Pages.AddPage(dialogPage, flex, true, true)
+	m.Application.SetFocus(primitive)
+}
+
+// closeDialog はダイアログを閉じてツリービューにフォーカスを戻す
+func (m *FilesView) closeDialog() {
+	m.Pages.RemovePage(dialogPage)
+	m.Application.SetFocus(m.TreeView)
+}
+
+// showInputDialog は 1 行の入力欄を持つダイアログを表示する
+// Enter で onSubmit が呼ばれ、Esc でキャンセルする
+func (m *FilesView) showInputDialog(title string, initial string, onSubmit func(text string)) {
+	inputField := tview.NewInputField().
+		SetText(initial).
+		SetFieldWidth(0)
+	inputField.SetBorder(true)
+	inputField.SetBorderColor(tcell.ColorDarkSlateGray)
+	inputField.SetTitle(" " + title + " ")
+
+	inputField.SetDoneFunc(func(key tcell.Key) {
+		text := inputField.GetText()
+		m.closeDialog()
+		if key == tcell.KeyEnter && text != "" {
+			onSubmit(text)
+		}
+	})
+
+	m.showDialog(inputField, 60, 3)
+}
+
+// showConfirmDialog は確認用のダイアログを表示する。OK が選ばれた場合だけ onConfirm が呼ばれる
+func (m *FilesView) showConfirmDialog(message string, okLabel string, onConfirm func()) {
+	modal := tview.NewModal().
+		SetText(message).
+		AddButtons([]string{okLabel, "Cancel"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			m.closeDialog()
+			if buttonLabel == okLabel {
+				onConfirm()
+			}
+		})
+
+	m.Pages.AddPage(dialogPage, modal, true, true)
+	m.Application.SetFocus(modal)
+}
+
+// showMessageDialog はエラーなどのメッセージを表示する
+func (m *FilesView) showMessageDialog(message string) {
+	modal := tview.NewModal().
+		SetText(message).
+		AddButtons([]string{"OK"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			m.closeDialog()
+		})
+
+	m.Pages.AddPage(dialogPage, modal, true, true)
+	m.Application.SetFocus(modal)
+}
@@ -0,0 +1,111 @@
+package files_view
+
+import (
+	"fmt"
+	"github.com/rivo/tview"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ディレクトリのプレビューに表示する最近更新したファイルの数
+const dirPreviewRecentFiles = 10
+
+// loadDirectoryPreview はディレクトリの概要をプレビューに表示する
+// 種類ごとのエントリ数、直下のファイルの合計サイズ、最近更新したファイル、README の内容を表示する
+func (m *FilesView) loadDirectoryPreview(path string) {
+	log.Printf("Loading directory preview: %s", path)
+	entries, err := m.readDir(path)
+	// 読み込んだエントリは情報パネルでも使う
+	if m.ShowInfo {
+		go m.loadDirectoryInfo(path, entries, err)
+	}
+	if err != nil {
+		m.ShowPreviewText(path, fmt.Sprintf("[red]Error loading directory: %v", err))
+		return
+	}
+
+	counts := countDirEntries(entries)
+	var totalSize int64
+	var recent []fs.FileInfo
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		totalSize += info.Size()
+		if info.Mode().IsRegular() {
+			recent = append(recent, info)
+		}
+	}
+
+	// 合計サイズにサブディレクトリの中身は含めない
+	lines := []string{
+		fmt.Sprintf("[yellow]%-12s[-] %d", "Files:", counts.files),
+		fmt.Sprintf("[yellow]%-12s[-] %d", "Directories:", counts.dirs),
+		fmt.Sprintf("[yellow]%-12s[-] %d", "Symlinks:", counts.symlinks),
+	}
+	if counts.others > 0 {
+		lines = append(lines, fmt.Sprintf("[yellow]%-12s[-] %d", "Others:", counts.others))
+	}
+	lines = append(lines, fmt.Sprintf("[yellow]%-12s[-] %s (%d bytes)", "Total size:", humanSize(totalSize), totalSize))
+
+	// リビジョンの表示中は更新日時がわからない
+	if !m.IsRevisionMode() && len(recent) > 0 {
+		slices.SortFunc(recent, func(a fs.FileInfo, b fs.FileInfo) int {
+			if c := b.ModTime().Compare(a.ModTime()); c != 0 {
+				return c
+			}
+			return strings.Compare(a.Name(), b.Name())
+		})
+		lines = append(lines, "", "[yellow]Recently modified:[-]")
+		for _, info := range recent[:min(len(recent), dirPreviewRecentFiles)] {
+			lines = append(lines, fmt.Sprintf("  %s %5s  %s",
+				formatModTime(info.ModTime()), humanSize(info.Size()), tview.Escape(info.Name())))
+		}
+	}
+
+	if readme := findReadme(entries); readme != "" {
+		readmePath := filepath.Join(path, readme)
+		content, err := m.readFile(readmePath)
+		if err != nil {
+			log.Printf("Failed to read %s: %v", readmePath, err)
+		} else if c := m.charsetOf(readmePath, content); c != nil {
+			if text, err := c.Decode(content); err == nil {
+				lines = append(lines, "", fmt.Sprintf("[yellow]── %s ──[-]", tview.Escape(readme)), "")
+				lines = append(lines, m.formatText(readmePath, string(text)))
+			}
+		}
+	}
+
+	m.ShowPreviewText(path, strings.Join(lines, "\n"))
+}
+
+// findReadme はディレクトリのエントリから README を探して、その名前を返す
+// 大文字と小文字は区別せず、README.md があればそれを優先する。なければ空文字列を返す
+func findReadme(entries []fs.DirEntry) string {
+	var found string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		base := strings.TrimSuffix(name, filepath.Ext(name))
+		if !strings.EqualFold(base, "readme") {
+			continue
+		}
+		if strings.EqualFold(name, "readme.md") {
+			return name
+		}
+		if found == "" {
+			found = name
+		}
+	}
+	return found
+}
@@ -0,0 +1,42 @@
+package files_view
+
+import (
+	"github.com/tokuhirom/mieta/mieta/charset"
+	"github.com/tokuhirom/mieta/mieta/config"
+	"log"
+	"path/filepath"
+)
+
+// DetectCharset は content の文字コードを返す。テキストでなければ nil を返す
+// 設定で relPath に文字コードが指定されていれば、判定せずにそれを使う
+func DetectCharset(config *config.Config, relPath string, content []byte) *charset.Charset {
+	if c := configuredCharset(config, relPath); c != nil {
+		return c
+	}
+	return charset.Detect(content)
+}
+
+// configuredCharset は設定で relPath に指定された文字コードを返す。指定がなければ nil を返す
+func configuredCharset(config *config.Config, relPath string) *charset.Charset {
+	name := config.EncodingFor(relPath)
+	if name == "" {
+		return nil
+	}
+
+	c, err := charset.Lookup(name)
+	if err != nil {
+		log.Printf("Invalid encoding for %s: %v", relPath, err)
+		return nil
+	}
+	return c
+}
+
+// charsetOf は path のファイルの文字コードを返す。テキストでなければ nil を返す
+func (m *FilesView) charsetOf(path string, content []byte) *charset.Charset {
+	return DetectCharset(m.Config, m.slashRelativePath(path), content)
+}
+
+// slashRelativePath は RootDir からの "/" 区切りの相対パスを返す
+func (m *FilesView) slashRelativePath(path string) string {
+	return filepath.ToSlash(m.relativePath(path))
+}
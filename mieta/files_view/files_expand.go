@@ -0,0 +1,147 @@
+package files_view
+
+import (
+	"fmt"
+	"github.com/rivo/tview"
+	"log"
+)
+
+// 再帰的に展開するときに同時に読み込むディレクトリの数
+const maxExpandWorkers = 8
+
+// expandJob は再帰的な展開の状態。コールバックはすべて UI スレッドで呼ばれる
+type expandJob struct {
+	// 展開する深さ。0 以下なら制限しない
+	maxDepth int
+	// これから読み込むディレクトリ
+	queue []expandItem
+	// 読み込み中のディレクトリの数
+	running int
+	// 展開したディレクトリの数
+	expanded int
+	canceled bool
+	// pumpExpansion の中から呼ばれたかどうか
+	pumping bool
+}
+
+type expandItem struct {
+	node  *tview.TreeNode
+	depth int
+}
+
+// selectedDirNode は選択中のディレクトリのノードを返す。ファイルが選択されていれば親ディレクトリを返す
+func (m *FilesView) selectedDirNode() *tview.TreeNode {
+	node, fileNode := m.selectedFileNode()
+	if fileNode == nil {
+		return nil
+	}
+	if fileNode.IsDir {
+		return node
+	}
+
+	path := m.TreeView.GetPath(node)
+	if len(path) < 2 {
+		return nil
+	}
+	return path[len(path)-2]
+}
+
+// expandRecursively は選択中のディレクトリを maxDepth の深さまで展開する。0 以下ならすべて展開する
+// ディレクトリは maxExpandWorkers 個ずつ並行して読み込み、Esc で中断できる
+func (m *FilesView) expandRecursively(maxDepth int) {
+	node := m.selectedDirNode()
+	if node == nil {
+		return
+	}
+
+	m.cancelExpansion()
+	job := &expandJob{
+		maxDepth: maxDepth,
+		queue:    []expandItem{{node: node, depth: 1}},
+	}
+	m.expandJob = job
+	m.pumpExpansion(job)
+}
+
+// pumpExpansion は読み込み中のディレクトリが maxExpandWorkers 個になるまで読み込みを始める
+func (m *FilesView) pumpExpansion(job *expandJob) {
+	// 読み込み済みのディレクトリはコールバックがすぐに呼ばれるので、ループの中から呼ばれたら何もしない
+	if job.pumping {
+		return
+	}
+	job.pumping = true
+	for !job.canceled && len(job.queue) > 0 && job.running < maxExpandWorkers {
+		item := job.queue[0]
+		job.queue = job.queue[1:]
+		job.running++
+		m.afterLoaded(item.node, func() {
+			m.expandLoaded(job, item)
+		})
+	}
+	job.pumping = false
+
+	if job.canceled || m.expandJob != job {
+		return
+	}
+	if job.running == 0 && len(job.queue) == 0 {
+		log.Printf("Expanded %d directories", job.expanded)
+		m.expandJob = nil
+		m.updateSortTitle()
+		return
+	}
+	m.TreeView.SetTitle(fmt.Sprintf(" expanding: %d dirs (Esc to cancel) ", job.expanded))
+}
+
+// expandLoaded は読み込み終わったディレクトリを展開して、子のディレクトリを読み込む対象に加える
+// finder と同じく、.git や ignore されたディレクトリは展開しない
+func (m *FilesView) expandLoaded(job *expandJob, item expandItem) {
+	job.running--
+	if job.canceled {
+		return
+	}
+
+	item.node.Expand()
+	job.expanded++
+	if job.maxDepth <= 0 || item.depth < job.maxDepth {
+		for _, child := range item.node.GetChildren() {
+			if fileNode, ok := child.GetReference().(*FileNode); ok && fileNode.IsDir && m.isSearchTarget(fileNode.Path, true) {
+				job.queue = append(job.queue, expandItem{node: child, depth: item.depth + 1})
+			}
+		}
+	}
+	m.pumpExpansion(job)
+}
+
+// cancelExpansion は再帰的な展開を中断する。中断した場合は true を返す
+// 読み込み中のディレクトリは読み込みを終えるが、展開はしない
+func (m *FilesView) cancelExpansion() bool {
+	job := m.expandJob
+	if job == nil {
+		return false
+	}
+
+	log.Printf("Canceled expanding after %d directories", job.expanded)
+	job.canceled = true
+	job.queue = nil
+	m.expandJob = nil
+	m.updateSortTitle()
+	return true
+}
+
+// collapseRecursively は選択中のディレクトリとその下のディレクトリをすべて折りたたむ
+// ファイルが選択されていれば親ディレクトリを折りたたんで選択する
+func (m *FilesView) collapseRecursively() {
+	node := m.selectedDirNode()
+	if node == nil {
+		return
+	}
+
+	m.cancelExpansion()
+	collapseAll(node)
+	if node != m.TreeView.GetRoot() {
+		node.Collapse()
+	}
+	if node != m.TreeView.GetCurrentNode() {
+		m.selectNode(node)
+	}
+}
@@ -0,0 +1,118 @@
+package files_view
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/tokuhirom/mieta/mieta/config"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestExpandRecursively(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Setenv("HOME", t.TempDir())
+
+	root := t.TempDir()
+	for _, dir := range []string{".git/objects/00", "a/b/c/d", "a/node_modules/pkg", "e"} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.FromSlash(dir)), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, ".gitignore"), []byte("node_modules/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		maxDepth int
+		want     []string
+	}{
+		// 選択したディレクトリを 1 段目として数える
+		{1, nil},
+		{2, []string{"a", "e"}},
+		{3, []string{"a", "a/b", "e"}},
+		// 0 なら制限しない。.git と ignore されたディレクトリは展開しない
+		{0, []string{"a", "a/b", "a/b/c", "a/b/c/d", "e"}},
+	}
+
+	for _, tt := range tests {
+		got := expandedAfter(t, root, tt.maxDepth)
+		var want []string
+		for _, dir := range tt.want {
+			want = append(want, filepath.Join(root, filepath.FromSlash(dir)))
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("expandRecursively(%d) expanded %v, want %v", tt.maxDepth, got, want)
+		}
+	}
+}
+
+// expandedAfter はルートを maxDepth の深さまで展開して、展開されたディレクトリを返す
+func expandedAfter(t *testing.T, root string, maxDepth int) []string {
+	t.Helper()
+
+	screen := tcell.NewSimulationScreen("UTF-8")
+	app := tview.NewApplication().SetScreen(screen)
+	pages := tview.NewPages()
+	view, err := NewFilesView(root, "", config.LoadConfig(), app, pages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer view.Close()
+	pages.AddPage("files", view.Flex, true, true)
+	app.SetRoot(pages, true)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := app.Run(); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer func() {
+		app.Stop()
+		<-done
+	}()
+
+	onUI(t, app, func() {
+		view.TreeView.SetCurrentNode(view.TreeView.GetRoot())
+		view.expandRecursively(maxDepth)
+	})
+
+	var expanded []string
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		finished := false
+		onUI(t, app, func() {
+			if view.expandJob == nil {
+				finished = true
+				expanded = view.expandedDirs()
+			}
+		})
+		if finished {
+			return expanded
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expandRecursively(%d) did not finish", maxDepth)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// onUI は fn を UI スレッドで実行して、終わるまで待つ
+func onUI(t *testing.T, app *tview.Application, fn func()) {
+	t.Helper()
+	done := make(chan struct{})
+	app.QueueUpdateDraw(func() {
+		fn()
+		close(done)
+	})
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the UI thread")
+	}
+}
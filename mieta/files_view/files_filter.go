@@ -0,0 +1,64 @@
+package files_view
+
+import (
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+// validateExcludePatterns は設定された除外パターンのうち、正しいものだけを返す
+func validateExcludePatterns(patterns []string) []string {
+	var valid []string
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			log.Printf("Invalid exclude pattern %q: %v", pattern, err)
+			continue
+		}
+		valid = append(valid, pattern)
+	}
+	return valid
+}
+
+// isExcluded は path が設定の除外パターンに一致するかを返す
+// "/" を含まないパターンはファイル名と、含むパターンは RootDir からの相対パスと比較する
+func (m *FilesView) isExcluded(path string) bool {
+	name := filepath.Base(path)
+	for _, pattern := range m.excludePatterns {
+		target := name
+		if strings.Contains(pattern, "/") {
+			target = filepath.ToSlash(m.relativePath(path))
+		}
+		if matched, _ := filepath.Match(pattern, target); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// isVisible は path をツリーに表示するかを返す
+func (m *FilesView) isVisible(path string, isDir bool) bool {
+	if m.isExcluded(path) {
+		return false
+	}
+	if !m.ShowHidden && strings.HasPrefix(filepath.Base(path), ".") {
+		return false
+	}
+	if m.HideIgnored && !m.IsRevisionMode() && m.gitTracker.IsIgnored(path, isDir) {
+		return false
+	}
+	return true
+}
+
+// toggleHidden はドットファイルを表示するかを切り替える
+func (m *FilesView) toggleHidden() {
+	m.ShowHidden = !m.ShowHidden
+	log.Printf("Show hidden files: %v", m.ShowHidden)
+	m.reloadTree()
+}
+
+// toggleHideIgnored は ignore されたファイルを隠すか、グレーで表示するかを切り替える
+func (m *FilesView) toggleHideIgnored() {
+	m.HideIgnored = !m.HideIgnored
+	log.Printf("Hide ignored files: %v", m.HideIgnored)
+	m.reloadTree()
+}
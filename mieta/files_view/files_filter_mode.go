@@ -0,0 +1,532 @@
+package files_view
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"log"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// treeFilter はフィルタモードの状態
+// パターンに一致しないノードは、親ノードの子から一時的に外して隠す
+type treeFilter struct {
+	pattern string
+	// 名前と RootDir からの相対パスを受け取り、一致するかを返す。パターンが空なら nil
+	match func(name string, relPath string) bool
+	// フィルタで子を外したノードと、外す前の子
+	pruned map[*tview.TreeNode][]*tview.TreeNode
+	// フィルタを始める前に展開していたディレクトリと選択していたパス
+	savedExpanded []string
+	savedSelected string
+	// パターンを変えるたびに増やす。古い検索の結果を捨てるために使う
+	generation int
+	// 一致したノードの数
+	count int
+}
+
+// compileFilterPattern はフィルタのパターンを一致判定の関数にする
+// "/.../" は正規表現、"*", "?", "[" を含むものは glob、それ以外は部分一致として扱う
+// "/" を含むパターンは RootDir からの相対パスと、含まないパターンはファイル名と比較する
+// パターンに大文字が含まれる場合だけ大文字と小文字を区別する
+func compileFilterPattern(pattern string) (func(name string, relPath string) bool, error) {
+	isRegexp := len(pattern) > 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/")
+	if isRegexp {
+		pattern = pattern[1 : len(pattern)-1]
+	}
+
+	ignoreCase := !strings.ContainsFunc(pattern, unicode.IsUpper)
+	usePath := strings.Contains(pattern, "/")
+	target := func(name string, relPath string) string {
+		if usePath {
+			name = relPath
+		}
+		if ignoreCase {
+			name = strings.ToLower(name)
+		}
+		return name
+	}
+
+	switch {
+	case isRegexp:
+		if ignoreCase {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return func(name string, relPath string) bool {
+			if usePath {
+				return re.MatchString(relPath)
+			}
+			return re.MatchString(name)
+		}, nil
+	case strings.ContainsAny(pattern, "*?["):
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+		return func(name string, relPath string) bool {
+			matched, _ := filepath.Match(pattern, target(name, relPath))
+			return matched
+		}, nil
+	default:
+		return func(name string, relPath string) bool {
+			return strings.Contains(target(name, relPath), pattern)
+		}, nil
+	}
+}
+
+// newFilterBox はフィルタモードの入力欄とステータス行を作る
+func (m *FilesView) newFilterBox() {
+	m.FilterBox = tview.NewInputField().
+		SetLabel("filter: ")
+	m.FilterStatusView = tview.NewTextView().
+		SetDynamicColors(true).
+		SetWrap(false)
+
+	m.FilterBox.SetChangedFunc(func(text string) {
+		m.applyFilter(text)
+	})
+	m.FilterBox.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEnter:
+			// フィルタをかけたままツリーに戻る。パターンが空ならフィルタモードを抜ける
+			if m.FilterBox.GetText() == "" {
+				m.clearFilter()
+			} else {
+				m.LeftPane.RemoveItem(m.FilterBox)
+				m.Application.SetFocus(m.TreeView)
+			}
+			return nil
+		case tcell.KeyEscape:
+			m.clearFilter()
+			return nil
+		default:
+			return event
+		}
+	})
+}
+
+// enterFilterMode はフィルタの入力欄を表示する。フィルタをかけている途中なら入力を続ける
+func (m *FilesView) enterFilterMode() {
+	if m.filter == nil {
+		dirs := m.expandedDirs()
+		selected := ""
+		if _, fileNode := m.selectedFileNode(); fileNode != nil {
+			selected = fileNode.Path
+		}
+		m.filter = &treeFilter{
+			pruned:        make(map[*tview.TreeNode][]*tview.TreeNode),
+			savedExpanded: dirs,
+			savedSelected: selected,
+		}
+		m.FilterBox.SetText("")
+		m.FilterStatusView.SetText("")
+		m.LeftPane.AddItem(m.FilterStatusView, 1, 0, false)
+	}
+
+	m.LeftPane.RemoveItem(m.FilterBox)
+	m.LeftPane.AddItem(m.FilterBox, 1, 0, true)
+	m.Application.SetFocus(m.FilterBox)
+}
+
+// clearFilter はフィルタを外して、フィルタを始める前の展開状態に戻す
+// 選択していたノードがあれば、そのノードを選択したままにする
+func (m *FilesView) clearFilter() {
+	filter := m.filter
+	if filter == nil {
+		return
+	}
+
+	selected := filter.savedSelected
+	if _, fileNode := m.selectedFileNode(); fileNode != nil && filter.pattern != "" {
+		selected = fileNode.Path
+	}
+
+	m.restorePrunedNodes()
+	m.filter = nil
+	m.LeftPane.RemoveItem(m.FilterBox)
+	m.LeftPane.RemoveItem(m.FilterStatusView)
+	m.Application.SetFocus(m.TreeView)
+
+	m.restoreExpansion(filter)
+	if selected != "" && isUnder(selected, m.RootDir) {
+		m.revealPath(selected)
+	} else {
+		m.selectNode(m.TreeView.GetRoot())
+	}
+}
+
+// restoreExpansion はディレクトリの展開状態をフィルタを始める前に戻す
+func (m *FilesView) restoreExpansion(filter *treeFilter) {
+	collapseAll(m.TreeView.GetRoot())
+	m.expandDirs(filter.savedExpanded)
+}
+
+// applyFilter はパターンを変えて、ツリーを絞り込み直す
+func (m *FilesView) applyFilter(pattern string) {
+	filter := m.filter
+	if filter == nil {
+		return
+	}
+
+	m.restorePrunedNodes()
+	filter.pattern = pattern
+	filter.match = nil
+	if pattern == "" {
+		filter.generation++
+		m.FilterStatusView.SetText("")
+		m.restoreExpansion(filter)
+		return
+	}
+
+	match, err := compileFilterPattern(pattern)
+	if err != nil {
+		filter.generation++
+		m.FilterStatusView.SetText("[red]Invalid pattern: " + tview.Escape(err.Error()))
+		return
+	}
+	filter.match = match
+	m.runFilter(true)
+}
+
+// runFilter はまだ読み込んでいないディレクトリも含めてすべて読み込んでから、ツリーを絞り込む
+// selectFirst なら最初に一致したノードを、そうでなければ隠れていない限り選択中のノードを選択する
+func (m *FilesView) runFilter(selectFirst bool) {
+	filter := m.filter
+	filter.generation++
+	generation := filter.generation
+	m.FilterStatusView.SetText("[yellow]Searching...")
+
+	// 読み込みを待っているディレクトリの数
+	pending := 0
+	var visit func(node *tview.TreeNode)
+	visit = func(node *tview.TreeNode) {
+		pending++
+		m.afterLoaded(node, func() {
+			if m.filter != filter || filter.generation != generation {
+				return
+			}
+			// .git や ignore されたディレクトリの中までは読み込まない
+			for _, child := range node.GetChildren() {
+				if fileNode, ok := child.GetReference().(*FileNode); ok && fileNode.IsDir && m.isSearchTarget(fileNode.Path, true) {
+					visit(child)
+				}
+			}
+
+			pending--
+			if pending == 0 {
+				m.pruneTree(selectFirst)
+			}
+		})
+	}
+	visit(m.TreeView.GetRoot())
+}
+
+// pruneTree は一致するノードと、一致するノードを含むディレクトリだけを残す
+// 一致したディレクトリの中身はすべて残す
+func (m *FilesView) pruneTree(selectFirst bool) {
+	filter := m.filter
+	root := m.TreeView.GetRoot()
+
+	var firstMatch *tview.TreeNode
+	_, count := m.pruneNode(root, false, func(node *tview.TreeNode) {
+		if firstMatch == nil {
+			firstMatch = node
+		}
+	})
+	filter.count = count
+	m.showFilterCount()
+	log.Printf("Filter %q: %d matches", filter.pattern, count)
+
+	current := m.TreeView.GetCurrentNode()
+	switch {
+	case !selectFirst && current != nil && m.TreeView.GetPath(current) != nil:
+		// 選択中のノードが残っていればそのまま
+	case firstMatch != nil:
+		m.selectNode(firstMatch)
+	default:
+		m.selectNode(root)
+	}
+}
+
+// pruneNode は node 以下の一致しないノードを親の子から外し、node を残すかと一致したノードの数を返す
+// keepAll なら node の子はすべて残す。一致したノードは見つけた順に onMatch に渡す
+func (m *FilesView) pruneNode(node *tview.TreeNode, keepAll bool, onMatch func(node *tview.TreeNode)) (bool, int) {
+	fileNode, ok := node.GetReference().(*FileNode)
+	if !ok {
+		// 読み込み中やエラーの表示
+		return false, 0
+	}
+
+	matched := m.filterMatches(node)
+	if matched && onMatch != nil {
+		onMatch(node)
+	}
+
+	keepChildren := keepAll || (matched && fileNode.IsDir)
+	children := node.GetChildren()
+	kept := make([]*tview.TreeNode, 0, len(children))
+	count := 0
+	for _, child := range children {
+		keep, n := m.pruneNode(child, keepChildren, onMatch)
+		count += n
+		if keep || keepChildren {
+			kept = append(kept, child)
+		}
+	}
+	if len(kept) != len(children) {
+		m.filter.pruned[node] = children
+		node.SetChildren(kept)
+	}
+
+	// 一致するノードを含むディレクトリだけを展開する
+	if fileNode.IsDir && node != m.TreeView.GetRoot() {
+		if count > 0 {
+			node.Expand()
+		} else {
+			node.Collapse()
+		}
+	}
+
+	if matched {
+		count++
+	}
+	return count > 0, count
+}
+
+// filterMatches は node がフィルタのパターンに一致するかを返す。ルートは一致させない
+func (m *FilesView) filterMatches(node *tview.TreeNode) bool {
+	fileNode, ok := node.GetReference().(*FileNode)
+	if !ok || node == m.TreeView.GetRoot() {
+		return false
+	}
+	relPath := filepath.ToSlash(m.relativePath(fileNode.Path))
+	return m.filter.match(filepath.Base(fileNode.Path), relPath)
+}
+
+// countFilterMatches は node 以下で表示されている、一致したノードの数を返す
+func (m *FilesView) countFilterMatches(node *tview.TreeNode) int {
+	count := 0
+	if m.filterMatches(node) {
+		count++
+	}
+	for _, child := range node.GetChildren() {
+		count += m.countFilterMatches(child)
+	}
+	return count
+}
+
+// hasFilterMatch は node 以下に表示されている、一致したノードがあるかを返す
+func (m *FilesView) hasFilterMatch(node *tview.TreeNode) bool {
+	if m.filterMatches(node) {
+		return true
+	}
+	return slices.ContainsFunc(node.GetChildren(), m.hasFilterMatch)
+}
+
+// showFilterCount は一致したノードの数を表示する
+func (m *FilesView) showFilterCount() {
+	switch m.filter.count {
+	case 0:
+		m.FilterStatusView.SetText("[red]No matches")
+	case 1:
+		m.FilterStatusView.SetText("[green]1 match")
+	default:
+		m.FilterStatusView.SetText(fmt.Sprintf("[green]%d matches", m.filter.count))
+	}
+}
+
+// restorePrunedNodes はフィルタで外した子をすべて元に戻す
+func (m *FilesView) restorePrunedNodes() {
+	if m.filter == nil {
+		return
+	}
+	for node, children := range m.filter.pruned {
+		node.SetChildren(children)
+	}
+	m.filter.pruned = make(map[*tview.TreeNode][]*tview.TreeNode)
+}
+
+// allChildren は node の子を、フィルタで外したものも含めて返す
+func (m *FilesView) allChildren(node *tview.TreeNode) []*tview.TreeNode {
+	if m.filter != nil {
+		if children, ok := m.filter.pruned[node]; ok {
+			return children
+		}
+	}
+	return node.GetChildren()
+}
+
+// restorePrunedSubtree は node 以下でフィルタで外した子を元に戻す
+func (m *FilesView) restorePrunedSubtree(node *tview.TreeNode) {
+	if children, ok := m.filter.pruned[node]; ok {
+		node.SetChildren(children)
+		delete(m.filter.pruned, node)
+	}
+	for _, child := range node.GetChildren() {
+		m.restorePrunedSubtree(child)
+	}
+}
+
+// editChildren は fn で parent の子を変更する
+// 絞り込み中なら、parent の中だけフィルタで外した子を戻してから変更し、parent の中だけ絞り込み直す
+// ファイルが増減するたびにツリー全体を絞り込み直さないようにする
+func (m *FilesView) editChildren(parent *tview.TreeNode, fn func()) {
+	if m.filter == nil || m.filter.match == nil {
+		fn()
+		return
+	}
+
+	before := m.countFilterMatches(parent)
+	m.restorePrunedSubtree(parent)
+	fn()
+	m.refilterNode(parent, before)
+}
+
+// refilterNode は node の中だけ絞り込み直し、node を残すかが変わった場合は上のディレクトリも直す
+// before は絞り込み直す前に node 以下で一致していたノードの数
+func (m *FilesView) refilterNode(node *tview.TreeNode, before int) {
+	filter := m.filter
+	fileNode, ok := node.GetReference().(*FileNode)
+	if !ok {
+		return
+	}
+	ancestors := m.loadedNodePath(fileNode.Path)
+	if len(ancestors) == 0 || ancestors[len(ancestors)-1] != node {
+		return
+	}
+	ancestors = ancestors[:len(ancestors)-1]
+
+	// 一致したディレクトリの中はすべて残す
+	keepChildren := make([]bool, len(ancestors))
+	keepAll := false
+	for i, ancestor := range ancestors {
+		keepAll = keepAll || m.filterMatches(ancestor)
+		keepChildren[i] = keepAll
+	}
+
+	keep, count := m.pruneNode(node, keepAll, nil)
+	filter.count += count - before
+	m.showFilterCount()
+
+	// 上のディレクトリの表示する子と展開を直す。一致するノードを含むかが変わらなくなったところで止める
+	matchedBefore, matchedAfter := before > 0, keep
+	child := node
+	for i := len(ancestors) - 1; i >= 0 && matchedBefore != matchedAfter; i-- {
+		parent := ancestors[i]
+
+		// ほかの子に一致するノードがあるか。一致したディレクトリの中でなければ、表示されている子がそれにあたる
+		othersMatched := false
+		for _, n := range parent.GetChildren() {
+			if n != child && (!keepChildren[i] || m.hasFilterMatch(n)) {
+				othersMatched = true
+				break
+			}
+		}
+
+		if !keepChildren[i] {
+			shown := make(map[*tview.TreeNode]bool)
+			for _, n := range parent.GetChildren() {
+				shown[n] = true
+			}
+			shown[child] = matchedAfter
+
+			all := m.allChildren(parent)
+			kept := make([]*tview.TreeNode, 0, len(all))
+			for _, n := range all {
+				if shown[n] {
+					kept = append(kept, n)
+				}
+			}
+			if len(kept) == len(all) {
+				delete(filter.pruned, parent)
+			} else {
+				filter.pruned[parent] = all
+			}
+			parent.SetChildren(kept)
+		}
+
+		if parent != m.TreeView.GetRoot() {
+			if othersMatched || matchedAfter {
+				parent.Expand()
+			} else {
+				parent.Collapse()
+			}
+		}
+
+		parentMatched := m.filterMatches(parent)
+		matchedBefore = parentMatched || othersMatched || matchedBefore
+		matchedAfter = parentMatched || othersMatched || matchedAfter
+		child = parent
+	}
+
+	// 選択中のノードが隠れた場合は、表示されている一番近い親を選択する
+	if current := m.TreeView.GetCurrentNode(); current == nil || m.TreeView.GetPath(current) == nil {
+		candidates := append(ancestors, node)
+		for i := len(candidates) - 1; i >= 0; i-- {
+			if i == 0 || m.TreeView.GetPath(candidates[i]) != nil {
+				m.selectNode(candidates[i])
+				break
+			}
+		}
+	}
+}
+
+// filterNewDir は絞り込み中に増えたディレクトリの中身を読み込み、一致するノードを探す
+// runFilter と同じく、.git や ignore されたディレクトリの中は読み込まない
+func (m *FilesView) filterNewDir(node *tview.TreeNode) {
+	filter := m.filter
+	if filter == nil || filter.match == nil {
+		return
+	}
+	if fileNode, ok := node.GetReference().(*FileNode); !ok || !m.isSearchTarget(fileNode.Path, true) {
+		return
+	}
+
+	generation := filter.generation
+	m.afterLoaded(node, func() {
+		if m.filter != filter || filter.generation != generation {
+			return
+		}
+		// 読み込んだ子は絞り込まずに追加されているので、数えていたのはディレクトリ自身だけ
+		before := 0
+		if m.filterMatches(node) {
+			before = 1
+		}
+		m.refilterNode(node, before)
+		for _, child := range m.allChildren(node) {
+			if fileNode, ok := child.GetReference().(*FileNode); ok && fileNode.IsDir {
+				m.filterNewDir(child)
+			}
+		}
+	})
+}
+
+// refilterTree はツリーを作り直したあとに、新しいノードに対して絞り込み直す
+func (m *FilesView) refilterTree() {
+	if m.filter == nil {
+		return
+	}
+
+	// 外した子は古いツリーのものなので捨てる
+	m.filter.pruned = make(map[*tview.TreeNode][]*tview.TreeNode)
+	if m.filter.match != nil {
+		m.runFilter(false)
+	}
+}
+
+// collapseAll は node 以下の読み込み済みのディレクトリをすべて折りたたむ
+func collapseAll(node *tview.TreeNode) {
+	for _, child := range node.GetChildren() {
+		if fileNode, ok := child.GetReference().(*FileNode); ok && fileNode.IsDir {
+			child.Collapse()
+			collapseAll(child)
+		}
+	}
+}
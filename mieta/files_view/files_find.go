@@ -0,0 +1,71 @@
+package files_view
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// findPositions は keyword の文字が text に順番に含まれていれば、その位置（rune 単位）を返す
+// 大文字と小文字は区別しない。含まれていなければ nil を返す
+func findPositions(keyword string, text string) []int {
+	if keyword == "" {
+		return nil
+	}
+
+	pattern := []rune(keyword)
+	positions := make([]int, 0, len(pattern))
+	for i, r := range []rune(text) {
+		if len(positions) < len(pattern) && unicode.ToLower(r) == unicode.ToLower(pattern[len(positions)]) {
+			positions = append(positions, i)
+		}
+	}
+	if len(positions) < len(pattern) {
+		return nil
+	}
+	return positions
+}
+
+// findNextMatch は検索モードで次に見つかったノードを選択する。最後まで行ったら最初に戻る
+func (m *FilesView) findNextMatch() {
+	m.moveFindMatch(1)
+}
+
+// findPrevMatch は検索モードで前に見つかったノードを選択する。最初まで行ったら最後に戻る
+func (m *FilesView) findPrevMatch() {
+	m.moveFindMatch(-1)
+}
+
+func (m *FilesView) moveFindMatch(delta int) {
+	if len(m.findMatches) == 0 {
+		return
+	}
+
+	m.findMatchIndex = (m.findMatchIndex + delta + len(m.findMatches)) % len(m.findMatches)
+	m.selectNode(m.findMatches[m.findMatchIndex])
+	m.updateFindLabel()
+}
+
+// updateFindLabel は検索欄のラベルに "3/17" の形式で何番目に見つかったノードかを表示する
+func (m *FilesView) updateFindLabel() {
+	label := "🔎: "
+	if m.FindingKeyword != "" {
+		current := 0
+		if len(m.findMatches) > 0 {
+			current = m.findMatchIndex + 1
+		}
+		label = fmt.Sprintf("🔎 %d/%d: ", current, len(m.findMatches))
+	}
+	m.FileNameSearchBox.SetLabel(label)
+}
+
+// leaveFindMode は検索モードを抜けて、ラベルの強調を元に戻す
+// cancel なら検索を始める前に選択していたノードに戻る
+func (m *FilesView) leaveFindMode(cancel bool) {
+	if cancel && m.NodeBeforeFinding != nil {
+		m.selectNode(m.NodeBeforeFinding)
+	}
+
+	m.FileNameSearchBox.SetText("")
+	m.LeftPane.RemoveItem(m.FileNameSearchBox)
+	m.Application.SetFocus(m.TreeView)
+}
@@ -0,0 +1,427 @@
+package files_view
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/tokuhirom/mieta/mieta/fuzzy"
+	"github.com/tokuhirom/mieta/mieta/git"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// finder に表示する結果の最大数
+const maxFinderResults = 100
+
+// 一覧を作っている途中で結果を更新する間隔
+const finderUpdateInterval = 200 * time.Millisecond
+
+// finderIndex は fuzzy finder で検索するファイルの一覧
+// 一覧はバックグラウンドで作り、ファイルが増減したら次に finder を開いたときに作り直す
+type finderIndex struct {
+	mutex sync.Mutex
+	// RootDir からの相対パス（"/" 区切り）
+	files []string
+	// 一覧を作り終えたかどうか
+	built bool
+	// 一覧を作っている途中かどうか
+	building bool
+	// ファイルが増減して、一覧を作り直す必要があるかどうか
+	stale bool
+	// 作り直すたびに増やす。古い一覧作りの結果を捨てるために使う
+	generation int
+}
+
+// finderResult は finder の検索結果の 1 件
+type finderResult struct {
+	path      string
+	score     int
+	positions []int
+}
+
+// fileFinder は開いている finder のポップアップ
+type fileFinder struct {
+	layout *tview.Flex
+	input  *tview.InputField
+	list   *tview.List
+	// 表示している結果
+	results []finderResult
+	// 検索するたびに増やす。古い検索結果を捨てるために使う
+	searchID int
+}
+
+// invalidateFinderIndex はファイルの一覧を古くなったことにする
+func (m *FilesView) invalidateFinderIndex() {
+	m.finderIndex.mutex.Lock()
+	defer m.finderIndex.mutex.Unlock()
+	m.finderIndex.stale = true
+}
+
+// finderFiles はファイルの一覧と、作っている途中かどうかを返す
+func (m *FilesView) finderFiles() ([]string, bool) {
+	m.finderIndex.mutex.Lock()
+	defer m.finderIndex.mutex.Unlock()
+	// 一覧には追加しかしないので、長さを固定したスライスは作っている途中でもそのまま使える
+	return m.finderIndex.files[:len(m.finderIndex.files):len(m.finderIndex.files)], m.finderIndex.building
+}
+
+// ensureFinderIndex はファイルの一覧がなければ、バックグラウンドで作り始める
+func (m *FilesView) ensureFinderIndex() {
+	index := &m.finderIndex
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	if !index.stale && (index.built || index.building) {
+		return
+	}
+
+	index.generation++
+	index.files = nil
+	index.built = false
+	index.building = true
+	index.stale = false
+	go m.buildFinderIndex(index.generation, m.RootDir)
+}
+
+// buildFinderIndex は rootDir 以下のファイルの一覧を作る
+// 作っている途中でも一定の間隔で finder の結果を更新する
+func (m *FilesView) buildFinderIndex(generation int, rootDir string) {
+	start := time.Now()
+	lastFlush := start
+	var batch []string
+
+	// 一覧に追加する。作り直しが始まっていたら false を返す
+	flush := func(done bool) bool {
+		index := &m.finderIndex
+		index.mutex.Lock()
+		if index.generation != generation {
+			index.mutex.Unlock()
+			return false
+		}
+		index.files = append(index.files, batch...)
+		if done {
+			index.building = false
+			index.built = true
+		}
+		index.mutex.Unlock()
+
+		batch = batch[:0]
+		lastFlush = time.Now()
+		m.Application.QueueUpdateDraw(m.refreshFinder)
+		return true
+	}
+	add := func(relPath string) bool {
+		batch = append(batch, relPath)
+		if time.Since(lastFlush) < finderUpdateInterval {
+			return true
+		}
+		return flush(false)
+	}
+
+	var err error
+	if m.IsRevisionMode() {
+		err = m.listRevisionFiles(rootDir, add)
+	} else {
+		err = m.walkFiles(rootDir, add)
+	}
+	if err != nil {
+		log.Printf("Error indexing files under %s: %v", rootDir, err)
+	}
+
+	if flush(true) {
+		log.Printf("Indexed files under %s in %v", rootDir, time.Since(start))
+	}
+}
+
+// errFinderCanceled は一覧作りを途中でやめるためのエラー
+var errFinderCanceled = errors.New("canceled")
+
+// walkFiles はワーキングツリーの rootDir 以下で、finder に表示するファイルを add に渡す
+// add が false を返したら途中でやめる
+func (m *FilesView) walkFiles(rootDir string, add func(relPath string) bool) error {
+	err := filepath.WalkDir(rootDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			log.Printf("Error walking %s: %v", path, err)
+			if entry != nil && entry.IsDir() && path != rootDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if path == rootDir {
+			return nil
+		}
+
+		if !m.isSearchTarget(path, entry.IsDir()) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(rootDir, path)
+		if err != nil {
+			return nil
+		}
+		if !add(filepath.ToSlash(relPath)) {
+			return errFinderCanceled
+		}
+		return nil
+	})
+	if err == errFinderCanceled {
+		return nil
+	}
+	return err
+}
+
+// listRevisionFiles は Revision の rootDir 以下で、finder に表示するファイルを add に渡す
+func (m *FilesView) listRevisionFiles(rootDir string, add func(relPath string) bool) error {
+	files, err := git.ListFiles(rootDir, m.revisionHash)
+	if err != nil {
+		return err
+	}
+
+	// ディレクトリごとの判定結果
+	skipDirs := make(map[string]bool)
+	skipped := func(relDir string) bool {
+		if relDir == "." {
+			return false
+		}
+		if skip, ok := skipDirs[relDir]; ok {
+			return skip
+		}
+		dir := filepath.Join(rootDir, filepath.FromSlash(relDir))
+		skip := !m.isSearchTarget(dir, true) || skipDirs[filepath.ToSlash(filepath.Dir(relDir))]
+		skipDirs[relDir] = skip
+		return skip
+	}
+
+	for _, relPath := range files {
+		// 親ディレクトリから順に判定する
+		dirs := strings.Split(relPath, "/")
+		skip := false
+		for i := 1; i < len(dirs) && !skip; i++ {
+			skip = skipped(strings.Join(dirs[:i], "/"))
+		}
+		if skip || !m.isSearchTarget(filepath.Join(rootDir, filepath.FromSlash(relPath)), false) {
+			continue
+		}
+		if !add(relPath) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// isSearchTarget は path を finder や絞り込みでたどる対象にするかを返す
+// ツリーの設定に関係なく、.git と ignore されたファイルは対象にしない
+func (m *FilesView) isSearchTarget(path string, isDir bool) bool {
+	if isDir && filepath.Base(path) == ".git" {
+		return false
+	}
+	if !m.isVisible(path, isDir) {
+		return false
+	}
+	if !m.IsRevisionMode() && m.gitTracker.IsIgnored(path, isDir) {
+		return false
+	}
+	return true
+}
+
+// openFinder はファイル名であいまい検索するポップアップを開く
+func (m *FilesView) openFinder() {
+	input := tview.NewInputField().
+		SetLabel("> ").
+		SetFieldWidth(0)
+	list := tview.NewList().
+		ShowSecondaryText(false).
+		SetHighlightFullLine(true)
+	layout := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(input, 1, 0, true).
+		AddItem(list, 0, 1, false)
+	layout.SetBorder(true)
+	layout.SetBorderColor(tcell.ColorDarkSlateGray)
+
+	finder := &fileFinder{layout: layout, input: input, list: list}
+	m.finder = finder
+
+	input.SetChangedFunc(func(text string) {
+		m.refreshFinder()
+	})
+	input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		count := list.GetItemCount()
+		switch event.Key() {
+		case tcell.KeyEsc:
+			m.closeFinder()
+			return nil
+		case tcell.KeyEnter:
+			m.chooseFinderResult()
+			return nil
+		case tcell.KeyUp, tcell.KeyCtrlP:
+			if count > 0 {
+				list.SetCurrentItem((list.GetCurrentItem() - 1 + count) % count)
+			}
+			return nil
+		case tcell.KeyDown, tcell.KeyCtrlN:
+			if count > 0 {
+				list.SetCurrentItem((list.GetCurrentItem() + 1) % count)
+			}
+			return nil
+		default:
+			return event
+		}
+	})
+
+	m.showDialog(layout, 80, 22)
+	m.ensureFinderIndex()
+	m.refreshFinder()
+}
+
+// closeFinder は finder を閉じる
+func (m *FilesView) closeFinder() {
+	m.finder = nil
+	m.closeDialog()
+}
+
+// chooseFinderResult は選択中の結果までツリーを展開して選択する
+func (m *FilesView) chooseFinderResult() {
+	finder := m.finder
+	index := finder.list.GetCurrentItem()
+	if index < 0 || index >= len(finder.results) {
+		return
+	}
+
+	path := filepath.Join(m.RootDir, filepath.FromSlash(finder.results[index].path))
+	m.closeFinder()
+	m.revealPath(path)
+}
+
+// refreshFinder は入力されたクエリで検索し直して、結果の一覧を更新する
+// 検索はバックグラウンドで行い、新しい検索が始まっていたら結果を捨てる
+func (m *FilesView) refreshFinder() {
+	finder := m.finder
+	if finder == nil {
+		return
+	}
+
+	finder.searchID++
+	searchID := finder.searchID
+	query := strings.TrimSpace(finder.input.GetText())
+	files, building := m.finderFiles()
+
+	go func() {
+		results := rankFiles(query, files)
+		m.Application.QueueUpdateDraw(func() {
+			if m.finder != finder || finder.searchID != searchID {
+				return
+			}
+			m.showFinderResults(results, len(files), building)
+		})
+	}()
+}
+
+// showFinderResults は検索結果を一覧に表示する
+func (m *FilesView) showFinderResults(results []finderResult, total int, building bool) {
+	finder := m.finder
+
+	// 一覧を作っている途中で更新されたときは、選択している結果を維持する
+	var selected string
+	if index := finder.list.GetCurrentItem(); index >= 0 && index < len(finder.results) {
+		selected = finder.results[index].path
+	}
+
+	finder.results = results
+	finder.list.Clear()
+	current := 0
+	for i, result := range results {
+		finder.list.AddItem(highlightPositions(result.path, result.positions), "", 0, nil)
+		if result.path == selected {
+			current = i
+		}
+	}
+	finder.list.SetCurrentItem(current)
+
+	status := fmt.Sprintf("%d/%d", len(results), total)
+	if building {
+		status += " indexing..."
+	}
+	finder.layout.SetTitle(fmt.Sprintf(" Find file (%s) ", status))
+}
+
+// rankFiles は query に一致するファイルを点数の高い順に返す
+// 同じ点数ならパスが短いものを先にする
+func rankFiles(query string, files []string) []finderResult {
+	if query == "" {
+		results := make([]finderResult, 0, min(len(files), maxFinderResults))
+		for _, path := range files[:min(len(files), maxFinderResults)] {
+			results = append(results, finderResult{path: path})
+		}
+		return results
+	}
+
+	var results []finderResult
+	for _, path := range files {
+		score, positions, ok := fuzzy.Match(query, path)
+		if ok {
+			results = append(results, finderResult{path: path, score: score, positions: positions})
+		}
+	}
+
+	slices.SortFunc(results, func(a finderResult, b finderResult) int {
+		if c := cmp.Compare(b.score, a.score); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(len(a.path), len(b.path)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.path, b.path)
+	})
+	if len(results) > maxFinderResults {
+		results = results[:maxFinderResults]
+	}
+	return results
+}
+
+// highlightPositions は text の positions の位置にある文字を強調したタグ付きの文字列を返す
+func highlightPositions(text string, positions []int) string {
+	matched := make(map[int]bool, len(positions))
+	for _, position := range positions {
+		matched[position] = true
+	}
+
+	// 一致した文字と一致しなかった文字のまとまりごとにエスケープする
+	var builder strings.Builder
+	var segment []rune
+	segmentMatched := false
+	writeSegment := func() {
+		if len(segment) == 0 {
+			return
+		}
+		if segmentMatched {
+			builder.WriteString("[yellow::b]" + tview.Escape(string(segment)) + "[-::-]")
+		} else {
+			builder.WriteString(tview.Escape(string(segment)))
+		}
+		segment = segment[:0]
+	}
+
+	for i, r := range []rune(text) {
+		if matched[i] != segmentMatched {
+			writeSegment()
+			segmentMatched = matched[i]
+		}
+		segment = append(segment, r)
+	}
+	writeSegment()
+	return builder.String()
+}
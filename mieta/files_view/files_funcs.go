@@ -4,12 +4,20 @@ import "log"
 
 // FilesScrollDown は preview を下にスクロールします
 func FilesScrollDown(view *FilesView) {
+	if view.showingHexDump() {
+		view.HexView.ScrollRows(9)
+		return
+	}
 	row, col := view.PreviewTextView.GetScrollOffset()
 	view.PreviewTextView.ScrollTo(row+9, col)
 }
 
 // FilesScrollUp は preview を上にスクロールします
 func FilesScrollUp(view *FilesView) {
+	if view.showingHexDump() {
+		view.HexView.ScrollRows(-9)
+		return
+	}
 	row, col := view.PreviewTextView.GetScrollOffset()
 	view.PreviewTextView.ScrollTo(row-9, col)
 }
@@ -78,6 +86,10 @@ func FilesExpand(view *FilesView) {
 
 // FilesScrollPageDown はプレビューを1ページ下にスクロールします
 func FilesScrollPageDown(view *FilesView) {
+	if view.showingHexDump() {
+		view.HexView.ScrollRows(view.HexView.PageRows())
+		return
+	}
 	row, col := view.PreviewTextView.GetScrollOffset()
 	_, _, _, height := view.PreviewTextView.GetRect()
 	if view.PreviewTextView.GetOriginalLineCount() <= row+height {
@@ -90,21 +102,21 @@ func FilesScrollPageDown(view *FilesView) {
 // FilesDecreaseTreeWidth はツリービューの幅を減らします
 func FilesDecreaseTreeWidth(view *FilesView) {
 	_, _, width, _ := view.LeftPane.GetRect()
-	view.Flex.ResizeItem(view.LeftPane, width-2, 1)
+	view.setTreeWidth(width - 2)
 }
 
 // FilesIncreaseTreeWidth はツリービューの幅を増やします
 func FilesIncreaseTreeWidth(view *FilesView) {
 	_, _, width, _ := view.LeftPane.GetRect()
-	view.Flex.ResizeItem(view.LeftPane, width+2, 1)
+	view.setTreeWidth(width + 2)
 }
 
 // FilesEnterFindMode は検索モードに入ります
 func FilesEnterFindMode(view *FilesView) {
+	view.NodeBeforeFinding = view.TreeView.GetCurrentNode()
 	view.FileNameSearchBox.SetText("")
 	view.LeftPane.AddItem(view.FileNameSearchBox, 1, 0, true)
 	view.Application.SetFocus(view.FileNameSearchBox)
-	view.NodeBeforeFinding = view.TreeView.GetCurrentNode()
 }
 
 func FilesInlineSearch(view *FilesView) {
@@ -120,3 +132,233 @@ func FilesFindNext(view *FilesView) {
 func FilesFindPrev(view *FilesView) {
 	view.findPrev()
 }
+
+// FilesToggleDiff はプレビューの表示を ソース → HEAD との diff → インデックスとの diff の順に切り替えます
+func FilesToggleDiff(view *FilesView) {
+	if view.IsRevisionMode() {
+		// ワーキングツリーとの diff なので、リビジョンの表示中は使えない
+		return
+	}
+
+	switch view.DiffMode {
+	case DiffOff:
+		view.DiffMode = DiffHead
+		view.BlameMode = false
+	case DiffHead:
+		view.DiffMode = DiffIndex
+	default:
+		view.DiffMode = DiffOff
+	}
+	view.reloadPreview()
+}
+
+// FilesShowHistory は選択されているファイルの履歴ページを表示します
+func FilesShowHistory(view *FilesView) {
+	node := view.TreeView.GetCurrentNode()
+	if node == nil || node.GetReference() == nil || view.OpenHistory == nil {
+		return
+	}
+
+	fileNode := node.GetReference().(*FileNode)
+	if fileNode.IsDir {
+		return
+	}
+	view.OpenHistory(fileNode.Path)
+}
+
+// FilesToggleBlame はプレビューの git blame 表示を切り替えます
+func FilesToggleBlame(view *FilesView) {
+	view.BlameMode = !view.BlameMode
+	if view.BlameMode {
+		view.DiffMode = DiffOff
+	}
+	view.reloadPreview()
+}
+
+// FilesCreateFile は新しいファイルを作成します
+func FilesCreateFile(view *FilesView) {
+	view.createFile()
+}
+
+// FilesCreateDirectory は新しいディレクトリを作成します
+func FilesCreateDirectory(view *FilesView) {
+	view.createDirectory()
+}
+
+// FilesRename は選択中のファイル/ディレクトリの名前を変更します
+func FilesRename(view *FilesView) {
+	view.renameSelected()
+}
+
+// FilesCopy は選択中のファイル/ディレクトリをコピーします
+func FilesCopy(view *FilesView) {
+	view.copySelected()
+}
+
+// FilesMove は選択中のファイル/ディレクトリを移動します
+func FilesMove(view *FilesView) {
+	view.moveSelected()
+}
+
+// FilesDelete は選択中のファイル/ディレクトリを削除します
+func FilesDelete(view *FilesView) {
+	view.deleteSelected()
+}
+
+// FilesToggleMark は選択中のノードのマークを切り替えます
+func FilesToggleMark(view *FilesView) {
+	view.toggleMark()
+}
+
+// FilesMarkSiblings は同じディレクトリのノードをすべてマークします
+func FilesMarkSiblings(view *FilesView) {
+	view.markSiblings()
+}
+
+// FilesInvertMarks は同じディレクトリのノードのマークを反転します
+func FilesInvertMarks(view *FilesView) {
+	view.invertMarks()
+}
+
+// FilesClearMarks はすべてのマークを外します
+func FilesClearMarks(view *FilesView) {
+	view.clearMarks()
+}
+
+// FilesEditMarked はマークされたファイルをまとめてエディタで開きます
+func FilesEditMarked(view *FilesView) {
+	view.editTargets()
+}
+
+// FilesCopyMarkedPaths はマークされたパスをクリップボードにコピーします
+func FilesCopyMarkedPaths(view *FilesView) {
+	view.copyTargetPaths()
+}
+
+// FilesDeleteMarked はマークされたファイル/ディレクトリを削除します
+func FilesDeleteMarked(view *FilesView) {
+	view.deleteTargets()
+}
+
+// FilesRunCommand はマークされたパスを渡して設定済みのコマンドを実行します
+func FilesRunCommand(view *FilesView) {
+	view.runCustomCommand()
+}
+
+// FilesYankAbsolutePath は選択中のノードの絶対パスをクリップボードにコピーします
+func FilesYankAbsolutePath(view *FilesView) {
+	view.yankAbsolutePath()
+}
+
+// FilesYankRelativePath は選択中のノードの相対パスをクリップボードにコピーします
+func FilesYankRelativePath(view *FilesView) {
+	view.yankRelativePath()
+}
+
+// FilesYankPathWithLine はプレビューで表示している行を path:line の形式でコピーします
+func FilesYankPathWithLine(view *FilesView) {
+	view.yankPathWithLine()
+}
+
+// FilesYankPreviewLines はプレビューに表示されている行をコピーします
+func FilesYankPreviewLines(view *FilesView) {
+	view.yankPreviewLines()
+}
+
+// FilesAddBookmark は選択中のノードをブックマークに追加します
+func FilesAddBookmark(view *FilesView) {
+	view.addBookmark()
+}
+
+// FilesShowBookmarks はブックマークのページを開きます
+func FilesShowBookmarks(view *FilesView) {
+	if view.OpenBookmarks != nil {
+		view.OpenBookmarks()
+	}
+}
+
+// FilesSetRoot は選択中のディレクトリをツリーのルートにします
+func FilesSetRoot(view *FilesView) {
+	view.setRootToSelected()
+}
+
+// FilesRootUp はツリーのルートを親ディレクトリに変更します
+func FilesRootUp(view *FilesView) {
+	view.moveRootUp()
+}
+
+// FilesRootBack は一つ前のルートディレクトリに戻ります
+func FilesRootBack(view *FilesView) {
+	view.backRoot()
+}
+
+// FilesCycleSortMode はツリーの並び順を切り替えます
+func FilesCycleSortMode(view *FilesView) {
+	view.cycleSortMode()
+}
+
+// FilesToggleSortReverse はツリーの並び順を逆にします
+func FilesToggleSortReverse(view *FilesView) {
+	view.toggleSortReverse()
+}
+
+// FilesToggleDirsFirst はディレクトリを先に表示するかを切り替えます
+func FilesToggleDirsFirst(view *FilesView) {
+	view.toggleDirsFirst()
+}
+
+// FilesToggleHidden はドットファイルを表示するかを切り替えます
+func FilesToggleHidden(view *FilesView) {
+	view.toggleHidden()
+}
+
+// FilesToggleHideIgnored は ignore されたファイルを隠すかを切り替えます
+func FilesToggleHideIgnored(view *FilesView) {
+	view.toggleHideIgnored()
+}
+
+// FilesToggleDetail はノードにサイズや更新日時などの列を表示するかを切り替えます
+func FilesToggleDetail(view *FilesView) {
+	view.toggleDetailMode()
+}
+
+// FilesToggleInfo はプレビューの下の情報パネルの表示を切り替えます
+func FilesToggleInfo(view *FilesView) {
+	view.toggleInfoPanel()
+}
+
+// FilesFuzzyFind はリポジトリ全体からファイルをあいまい検索するポップアップを開きます
+func FilesFuzzyFind(view *FilesView) {
+	view.openFinder()
+}
+
+// FilesEnterFilterMode はパターンに一致しないノードを隠すフィルタモードに入ります
+func FilesEnterFilterMode(view *FilesView) {
+	view.enterFilterMode()
+}
+
+// FilesExpandRecursively は選択中のディレクトリを設定した深さまで再帰的に展開します
+func FilesExpandRecursively(view *FilesView) {
+	view.expandRecursively(view.Config.Tree.ExpandDepth)
+}
+
+// FilesExpandAll は選択中のディレクトリ以下をすべて展開します
+func FilesExpandAll(view *FilesView) {
+	view.expandRecursively(0)
+}
+
+// FilesCollapseRecursively は選択中のディレクトリ以下をすべて折りたたみます
+func FilesCollapseRecursively(view *FilesView) {
+	view.collapseRecursively()
+}
+
+// FilesToggleMarkdown は Markdown のプレビューを整形して表示するかソースのまま表示するかを切り替えます
+// 切り替えた表示方法は拡張子ごとに保存されます
+func FilesToggleMarkdown(view *FilesView) {
+	view.toggleMarkdownRendering()
+}
+
+// FilesHexGoToOffset は 16 進ダンプを入力したオフセットまでスクロールします
+func FilesHexGoToOffset(view *FilesView) {
+	view.goToHexOffset()
+}
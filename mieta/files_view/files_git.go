@@ -0,0 +1,378 @@
+package files_view
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/tokuhirom/mieta/mieta/config"
+	"github.com/tokuhirom/mieta/mieta/git"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gitStatusRefreshDelay は fsnotify のイベントが落ち着くまで git status の実行を待つ時間
+const gitStatusRefreshDelay = 300 * time.Millisecond
+
+// gitStatusPollInterval は git status を定期的に取得し直す間隔
+// 展開していないディレクトリは監視しないので、その中の変更はこの間隔で反映する
+const gitStatusPollInterval = 5 * time.Second
+
+// pollGitStatus は Close されるまで git status を定期的に取得し直す
+func (m *FilesView) pollGitStatus() {
+	ticker := time.NewTicker(gitStatusPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.refreshGitStatus()
+		case <-m.closed:
+			return
+		}
+	}
+}
+
+// refreshGitStatus は git status を取得し直してツリーの表示を更新する
+func (m *FilesView) refreshGitStatus() {
+	changed, err := m.gitTracker.RefreshStatus(m.RootDir)
+	if err != nil {
+		log.Printf("Error refreshing git status: %v", err)
+		return
+	}
+
+	// .git/index の更新を検知するために .git ディレクトリ自体を監視する
+	// .git の中はツリーでは監視しないので、info/exclude のあるディレクトリもここで監視する
+	gitDir := m.gitTracker.GitDir(m.RootDir)
+	gitInfoDir := ""
+	if excludeFile := m.gitTracker.InfoExcludeFile(m.RootDir); excludeFile != "" {
+		gitInfoDir = filepath.Dir(excludeFile)
+	}
+	m.watcherMutex.Lock()
+	// ルートが別のリポジトリに移った場合は、前のリポジトリの .git の監視をやめる
+	if m.gitDir != gitDir {
+		m.unwatchDir(m.gitDir)
+		// .git の場所がわかる前に読み込んで監視した .git の中のディレクトリの監視もやめる
+		for path := range m.watchedDirs {
+			if gitDir != "" && path != gitDir && path != gitInfoDir && isUnder(path, gitDir) {
+				m.unwatchDir(path)
+			}
+		}
+	}
+	if m.gitInfoDir != gitInfoDir {
+		m.unwatchDir(m.gitInfoDir)
+	}
+	m.watchGitDir(gitDir)
+	m.watchGitDir(gitInfoDir)
+	m.gitDir = gitDir
+	m.gitInfoDir = gitInfoDir
+	m.watcherMutex.Unlock()
+
+	if !changed {
+		return
+	}
+	// 監視していないディレクトリでファイルが増えたり消えたりしたかもしれないので、ファイルの一覧も作り直す
+	m.invalidateFinderIndex()
+	m.Application.QueueUpdateDraw(func() {
+		m.refreshNodeStyles(m.TreeView.GetRoot())
+	})
+}
+
+// watchGitDir は .git の中のディレクトリを監視する。watcherMutex を取得した状態で呼ぶこと
+func (m *FilesView) watchGitDir(dir string) {
+	if dir == "" || m.watcher == nil || m.watchedDirs[dir] {
+		return
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return
+	}
+
+	if err := m.watcher.Add(dir); err != nil {
+		log.Printf("Error watching git directory %s: %v", dir, err)
+		return
+	}
+	m.watchedDirs[dir] = true
+}
+
+// ignoreRulesChanged は ignore ルールが変わったときに、読み込み済みのノードの色を更新する
+// ignore されたファイルを隠している場合は表示するファイルが変わるので読み込み直す
+func (m *FilesView) ignoreRulesChanged() {
+	m.invalidateFinderIndex()
+	m.Application.QueueUpdateDraw(func() {
+		if m.HideIgnored {
+			m.reloadTree()
+		} else {
+			m.refreshNodeStyles(m.TreeView.GetRoot())
+		}
+	})
+}
+
+// scheduleGitStatusRefresh は少し待ってから git status を取得し直す
+// 連続したイベントはまとめて 1 回の実行にする
+func (m *FilesView) scheduleGitStatusRefresh() {
+	m.gitStatusMutex.Lock()
+	defer m.gitStatusMutex.Unlock()
+
+	if m.gitStatusTimer != nil {
+		m.gitStatusTimer.Stop()
+	}
+	m.gitStatusTimer = time.AfterFunc(gitStatusRefreshDelay, m.refreshGitStatus)
+}
+
+// gitStatusColor は状態に応じたノードの色を返す
+func gitStatusColor(status git.FileStatus) tcell.Color {
+	switch {
+	case status&git.StatusConflicted != 0:
+		return tcell.ColorFuchsia
+	case status&git.StatusDeleted != 0:
+		return tcell.ColorRed
+	case status&git.StatusModified != 0:
+		return tcell.ColorYellow
+	case status&git.StatusRenamed != 0:
+		return tcell.ColorAqua
+	case status&git.StatusStaged != 0:
+		return tcell.ColorGreen
+	case status&git.StatusUntracked != 0:
+		return tcell.ColorOrange
+	default:
+		return tcell.ColorWhite
+	}
+}
+
+// gitStatusBadge は状態を表す短い記号を返す（例: "M", "+M"）
+func gitStatusBadge(status git.FileStatus) string {
+	badge := ""
+	if status&git.StatusConflicted != 0 {
+		badge += "!"
+	}
+	if status&git.StatusStaged != 0 && status&git.StatusRenamed == 0 {
+		badge += "+"
+	}
+	if status&git.StatusRenamed != 0 {
+		badge += "R"
+	}
+	if status&git.StatusModified != 0 {
+		badge += "M"
+	}
+	if status&git.StatusDeleted != 0 {
+		badge += "D"
+	}
+	if status&git.StatusUntracked != 0 {
+		badge += "?"
+	}
+	return badge
+}
+
+// DiffMode はプレビューに表示する diff の種類
+type DiffMode int
+
+const (
+	DiffOff DiffMode = iota
+	DiffHead
+	DiffIndex
+)
+
+var hunkHeaderRegexp = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// loadDiff は HEAD またはインデックスとの差分をプレビューに表示する
+func (m *FilesView) loadDiff(path string) {
+	target := git.DiffAgainstHead
+	if m.DiffMode == DiffIndex {
+		target = git.DiffAgainstIndex
+	}
+
+	var diff string
+	var err error
+	if m.gitTracker.Status(path)&git.StatusUntracked != 0 {
+		diff, err = git.DiffUntracked(path)
+	} else {
+		diff, err = git.Diff(path, target)
+	}
+
+	var text string
+	var lineNumbers []int
+	if err != nil {
+		text = fmt.Sprintf("[red]Error loading diff: %v", tview.Escape(err.Error()))
+	} else if diff == "" {
+		text = fmt.Sprintf("[yellow]No changes against %s", target)
+	} else {
+		text, lineNumbers = RenderDiff(diff)
+	}
+
+	m.Application.QueueUpdateDraw(func() {
+		if m.CurrentLoadingFile != path {
+			log.Printf("Ignoring diff: %s", path)
+			return
+		}
+		m.diffLineNumbers = lineNumbers
+		m.PreviewTextView.SetTitle(fmt.Sprintf("%s (diff %s)", m.previewTitle(path), target))
+		m.PreviewTextView.SetText(text)
+		m.PreviewTextView.ScrollToBeginning()
+		m.PreviewPages.SwitchToPage("text")
+	})
+}
+
+// RenderDiff は diff に色を付け、表示上の各行に対応するファイルの行番号を返す
+func RenderDiff(diff string) (string, []int) {
+	lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+	lineNumbers := make([]int, len(lines))
+
+	var buf strings.Builder
+	newLine := 1
+	inHunk := false
+	for i, line := range lines {
+		escaped := tview.Escape(line)
+		switch {
+		case strings.HasPrefix(line, "diff --git"):
+			inHunk = false
+			lineNumbers[i] = newLine
+			buf.WriteString("[::b]" + escaped + "[::-]")
+		case strings.HasPrefix(line, "@@"):
+			if matches := hunkHeaderRegexp.FindStringSubmatch(line); matches != nil {
+				newLine, _ = strconv.Atoi(matches[1])
+				if newLine == 0 {
+					newLine = 1
+				}
+			}
+			inHunk = true
+			lineNumbers[i] = newLine
+			buf.WriteString("[aqua]" + escaped + "[-]")
+		case !inHunk:
+			lineNumbers[i] = newLine
+			buf.WriteString("[::b]" + escaped + "[::-]")
+		case strings.HasPrefix(line, "+"):
+			lineNumbers[i] = newLine
+			newLine++
+			buf.WriteString("[green]" + escaped + "[-]")
+		case strings.HasPrefix(line, "-"):
+			lineNumbers[i] = newLine
+			buf.WriteString("[red]" + escaped + "[-]")
+		case strings.HasPrefix(line, `\`):
+			lineNumbers[i] = max(newLine-1, 1)
+			buf.WriteString("[gray]" + escaped + "[-]")
+		default:
+			lineNumbers[i] = newLine
+			newLine++
+			buf.WriteString(escaped)
+		}
+		buf.WriteString("\n")
+	}
+
+	return buf.String(), lineNumbers
+}
+
+// diffLineNumber は diff 表示上の行番号（1 始まり）をファイルの行番号に変換する
+func (m *FilesView) diffLineNumber(displayLine int) int {
+	if len(m.diffLineNumbers) == 0 {
+		return 1
+	}
+	index := min(max(displayLine-1, 0), len(m.diffLineNumbers)-1)
+	return m.diffLineNumbers[index]
+}
+
+// blameAuthorWidth は blame の gutter に表示する author の最大幅
+const blameAuthorWidth = 16
+
+// canBlame は blame を表示できるテキストファイルかを返す
+func (m *FilesView) canBlame(path string) bool {
+	if isImageFile(path) {
+		return false
+	}
+	// リビジョンの表示中はワーキングツリーのファイルを見ても判定できないので、その時点の内容で判定する
+	if m.IsRevisionMode() {
+		content, err := m.readFile(path)
+		return err != nil || m.charsetOf(path, content) != nil
+	}
+	if configuredCharset(m.Config, m.slashRelativePath(path)) != nil {
+		return true
+	}
+	binary, err := isBinaryFile(path)
+	return err != nil || !binary
+}
+
+// loadBlame は git blame の情報を gutter に付けてプレビューに表示する
+func (m *FilesView) loadBlame(config *config.Config, path string) {
+	lines, err := git.Blame(path, m.revisionHash)
+	if err != nil {
+		m.ShowPreviewText(path, fmt.Sprintf("[red]%s", tview.Escape(err.Error())))
+		return
+	}
+
+	contents := make([]string, len(lines))
+	for i, line := range lines {
+		contents[i] = line.Content
+	}
+	highlighted := strings.Split(HighlightContent(config, path, strings.Join(contents, "\n")), "\n")
+
+	authorWidth := 0
+	for _, line := range lines {
+		if line.Commit != nil {
+			authorWidth = max(authorWidth, tview.TaggedStringWidth(tview.Escape(line.Commit.Author)))
+		}
+	}
+	authorWidth = min(authorWidth, blameAuthorWidth)
+	gutterWidth := 7 + 1 + authorWidth + 1 + 10
+
+	var buf strings.Builder
+	var prevCommit *git.BlameCommit
+	group := 0
+	for i, line := range lines {
+		if line.Commit != prevCommit {
+			group++
+		}
+
+		// 同じコミットが続く行は情報を省略して、色を交互に変えてまとまりを示す
+		color := "yellow"
+		if group%2 == 0 {
+			color = "aqua"
+		}
+		var gutter string
+		if line.Commit == nil {
+			gutter = strings.Repeat(" ", gutterWidth)
+		} else if line.Commit != prevCommit {
+			gutter = fmt.Sprintf("%s %s %s",
+				line.Commit.ShortHash(),
+				padRight(truncate(line.Commit.Author, authorWidth), authorWidth),
+				blameDate(line.Commit))
+		} else {
+			gutter = strings.Repeat(" ", gutterWidth-1) + "┆"
+		}
+		prevCommit = line.Commit
+
+		text := line.Content
+		if i < len(highlighted) {
+			text = highlighted[i]
+		}
+		buf.WriteString(fmt.Sprintf("[%s]%s[-] │ %s\n", color, tview.Escape(gutter), text))
+	}
+
+	m.ShowPreviewText(path, buf.String())
+}
+
+func blameDate(commit *git.BlameCommit) string {
+	if commit.IsUncommitted() {
+		return "(uncommit)"
+	}
+	return commit.AuthorTime.Format("2006-01-02")
+}
+
+// truncate は表示幅が width を超える場合に切り詰める
+func truncate(text string, width int) string {
+	runes := []rune(text)
+	for len(runes) > 0 && tview.TaggedStringWidth(tview.Escape(string(runes))) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes)
+}
+
+// padRight は表示幅が width になるまで空白を追加する
+func padRight(text string, width int) string {
+	padding := width - tview.TaggedStringWidth(tview.Escape(text))
+	if padding <= 0 {
+		return text
+	}
+	return text + strings.Repeat(" ", padding)
+}
@@ -0,0 +1,299 @@
+package files_view
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/tokuhirom/mieta/mieta/charset"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 16 進ダンプの 1 行に表示するバイト数
+const hexBytesPerRow = 16
+
+// バイナリかどうかを判定するために読む先頭のバイト数
+const binarySniffSize = 8000
+
+// HexView はバイナリファイルをオフセット、16 進数、ASCII の列で表示する
+// 表示している範囲だけを読み込むので、大きなファイルでもすぐに開ける
+type HexView struct {
+	*tview.Box
+
+	reader io.ReaderAt
+	// 表示を切り替えたときに閉じるファイル。なければ nil
+	closer io.Closer
+	size   int64
+	// 先頭に表示している行のオフセット。hexBytesPerRow の倍数
+	offset int64
+	// 強調するバイトのオフセット。なければ -1
+	mark int64
+
+	// 最後に読み込んだ範囲
+	buffer       []byte
+	bufferOffset int64
+}
+
+func NewHexView() *HexView {
+	return &HexView{
+		Box:  tview.NewBox(),
+		mark: -1,
+	}
+}
+
+// SetData は表示するデータを切り替えて先頭に戻る。closer は次に切り替えたときに閉じる
+func (v *HexView) SetData(reader io.ReaderAt, size int64, closer io.Closer) {
+	v.Close()
+	v.reader = reader
+	v.closer = closer
+	v.size = size
+	v.offset = 0
+	v.mark = -1
+}
+
+// Close は開いているファイルを閉じて、何も表示しない状態にする
+func (v *HexView) Close() {
+	if v.closer != nil {
+		if err := v.closer.Close(); err != nil {
+			log.Printf("Failed to close hex dump: %v", err)
+		}
+	}
+	v.reader = nil
+	v.closer = nil
+	v.size = 0
+	v.buffer = nil
+}
+
+// Offset は先頭に表示している行のオフセットを返す
+func (v *HexView) Offset() int64 {
+	return v.offset
+}
+
+// PageRows は 1 画面に表示できる行数を返す
+func (v *HexView) PageRows() int {
+	_, _, _, height := v.GetInnerRect()
+	return max(height, 1)
+}
+
+// ScrollRows は rows 行スクロールする。負の値なら上にスクロールする
+func (v *HexView) ScrollRows(rows int) {
+	v.ScrollTo(v.offset + int64(rows)*hexBytesPerRow)
+}
+
+// ScrollTo は offset を含む行が先頭に来るようにスクロールする
+// 最後の行が一番下に来るところより先にはスクロールしない
+func (v *HexView) ScrollTo(offset int64) {
+	lastRow := max(v.size-1, 0) / hexBytesPerRow
+	maxOffset := max(lastRow-int64(v.PageRows()-1), 0) * hexBytesPerRow
+	offset = min(max(offset, 0), maxOffset)
+	v.offset = offset - offset%hexBytesPerRow
+}
+
+// GoTo は offset のバイトを強調して、そのバイトが見えるようにスクロールする
+func (v *HexView) GoTo(offset int64) {
+	if v.size == 0 {
+		return
+	}
+	v.mark = min(max(offset, 0), v.size-1)
+	v.ScrollTo(v.mark)
+}
+
+// read は offset から n バイトを読み込む。同じ範囲ならファイルを読み直さない
+func (v *HexView) read(offset int64, n int) []byte {
+	if v.buffer != nil && v.bufferOffset == offset && len(v.buffer) >= int(min(int64(n), v.size-offset)) {
+		return v.buffer[:min(len(v.buffer), n)]
+	}
+
+	buffer := make([]byte, n)
+	read, err := v.reader.ReadAt(buffer, offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		log.Printf("Failed to read hex dump at %d: %v", offset, err)
+	}
+	v.buffer = buffer[:read]
+	v.bufferOffset = offset
+	return v.buffer
+}
+
+func (v *HexView) Draw(screen tcell.Screen) {
+	v.Box.DrawForSubclass(screen, v)
+	if v.reader == nil {
+		return
+	}
+
+	x, y, width, height := v.GetInnerRect()
+	data := v.read(v.offset, height*hexBytesPerRow)
+	// オフセットの桁数は、ファイルの最後のオフセットに合わせる
+	digits := max(8, len(strconv.FormatInt(max(v.size-1, 0), 16)))
+	for row := 0; row < height && row*hexBytesPerRow < len(data); row++ {
+		start := row * hexBytesPerRow
+		chunk := data[start:min(start+hexBytesPerRow, len(data))]
+		line := v.formatRow(v.offset+int64(start), chunk, digits)
+		tview.Print(screen, line, x, y+row, width, tview.AlignLeft, tcell.ColorDefault)
+	}
+}
+
+// formatRow は 1 行分のオフセット、16 進数、ASCII の列を色タグ付きで返す
+func (v *HexView) formatRow(offset int64, chunk []byte, digits int) string {
+	var line strings.Builder
+	fmt.Fprintf(&line, "[gray]%0*x[-]  ", digits, offset)
+	for i := range hexBytesPerRow {
+		if i == hexBytesPerRow/2 {
+			line.WriteString(" ")
+		}
+		if i >= len(chunk) {
+			line.WriteString("   ")
+			continue
+		}
+		fmt.Fprintf(&line, "%s%02x[-::-] ", v.byteTag(offset+int64(i), chunk[i]), chunk[i])
+	}
+
+	line.WriteString("[gray]│[-]")
+	for i, c := range chunk {
+		char := "."
+		if c >= 0x20 && c < 0x7f {
+			char = tview.Escape(string(rune(c)))
+		}
+		line.WriteString(v.byteTag(offset+int64(i), c) + char + "[-::-]")
+	}
+	line.WriteString("[gray]│[-]")
+	return line.String()
+}
+
+// byteTag はバイトの種類（NUL、表示できる ASCII、制御文字、0x80 以上）に応じた色タグを返す
+func (v *HexView) byteTag(offset int64, c byte) string {
+	var color string
+	switch {
+	case c == 0:
+		color = "gray"
+	case c >= 0x20 && c < 0x7f:
+		color = "aqua"
+	case c < 0x80:
+		color = "green"
+	default:
+		color = "yellow"
+	}
+	if offset == v.mark {
+		return "[" + color + "::r]"
+	}
+	return "[" + color + "]"
+}
+
+// isBinaryFile はファイルの先頭だけを読んで、バイナリファイルかを判定する
+func isBinaryFile(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	head := make([]byte, binarySniffSize)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return false, err
+	}
+	if n == binarySniffSize {
+		return charset.DetectPrefix(head) == nil, nil
+	}
+	return charset.Detect(head[:n]) == nil, nil
+}
+
+// loadHexDump はファイルを開いたまま、表示する範囲だけを読み込む 16 進ダンプを表示する
+func (m *FilesView) loadHexDump(path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		m.ShowPreviewText(path, fmt.Sprintf("[red]Error loading file: %v", err))
+		return
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		m.ShowPreviewText(path, fmt.Sprintf("[red]Error loading file: %v", err))
+		return
+	}
+	m.showHexDump(path, file, info.Size(), file)
+}
+
+// showHexDump は 16 進ダンプをプレビューに表示する。同じファイルを読み直した場合はスクロール位置を保つ
+func (m *FilesView) showHexDump(path string, reader io.ReaderAt, size int64, closer io.Closer) {
+	m.Application.QueueUpdateDraw(func() {
+		if m.CurrentLoadingFile != path {
+			log.Printf("Ignoring hex dump: %s", path)
+			if closer != nil {
+				closer.Close()
+			}
+			return
+		}
+
+		log.Printf("Displaying hex dump: %s(%d bytes)", path, size)
+		offset := int64(0)
+		if m.hexPath == path {
+			offset = m.HexView.Offset()
+		}
+		m.HexView.SetData(reader, size, closer)
+		m.HexView.ScrollTo(offset)
+		m.HexView.SetTitle(fmt.Sprintf("%s (hex, %s)", m.previewTitle(path), humanSize(size)))
+		m.hexPath = path
+		m.PreviewPages.SwitchToPage("hex")
+	})
+}
+
+// closeHexDump は 16 進ダンプで開いているファイルを閉じる
+func (m *FilesView) closeHexDump() {
+	m.HexView.Close()
+	m.hexPath = ""
+}
+
+// showingHexDump はプレビューに 16 進ダンプを表示しているかを返す
+func (m *FilesView) showingHexDump() bool {
+	name, _ := m.PreviewPages.GetFrontPage()
+	return name == "hex"
+}
+
+// goToHexOffset は入力したオフセットまで 16 進ダンプをスクロールする
+func (m *FilesView) goToHexOffset() {
+	if !m.showingHexDump() {
+		return
+	}
+
+	m.showInputDialog("Go to offset (1234, 0x4d2, 50%, -16)", "", func(text string) {
+		offset, err := parseHexOffset(text, m.HexView.size)
+		if err != nil {
+			m.showMessageDialog(tview.Escape(err.Error()))
+			return
+		}
+		m.HexView.GoTo(offset)
+	})
+}
+
+// parseHexOffset はオフセットの指定を解釈する
+// 10 進数、"0x" で始まる 16 進数、ファイルサイズに対する割合（"50%"）、末尾からの位置（"-16"）を受け付ける
+func parseHexOffset(text string, size int64) (int64, error) {
+	text = strings.TrimSpace(text)
+	if percent, ok := strings.CutSuffix(text, "%"); ok {
+		value, err := strconv.ParseFloat(percent, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid offset %q", text)
+		}
+		return int64(float64(size) * value / 100), nil
+	}
+
+	fromEnd := strings.HasPrefix(text, "-")
+	digits := strings.TrimPrefix(text, "-")
+	base := 10
+	if lower := strings.ToLower(digits); strings.HasPrefix(lower, "0x") {
+		digits = digits[2:]
+		base = 16
+	}
+	value, err := strconv.ParseInt(digits, base, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid offset %q", text)
+	}
+	if fromEnd {
+		return size - value, nil
+	}
+	return value, nil
+}
@@ -0,0 +1,248 @@
+package files_view
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/rivo/tview"
+	"github.com/tokuhirom/mieta/mieta/git"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// infoPanelHeight は情報パネルの高さ（枠線を含む）
+const infoPanelHeight = 13
+
+// toggleInfoPanel はプレビューの下の情報パネルの表示を切り替える
+func (m *FilesView) toggleInfoPanel() {
+	m.ShowInfo = !m.ShowInfo
+	if m.ShowInfo {
+		m.PreviewColumn.AddItem(m.InfoTextView, infoPanelHeight, 0, false)
+		m.reloadPreview()
+	} else {
+		m.PreviewColumn.RemoveItem(m.InfoTextView)
+		m.infoPath = ""
+	}
+}
+
+// startInfo は path の情報の読み込みを始めたことを記録して、パネルを読み込み中の表示にする
+func (m *FilesView) startInfo(path string) {
+	m.infoPath = path
+	m.InfoTextView.SetTitle(" Info ")
+	m.InfoTextView.SetText("[blue]Loading...")
+}
+
+// setInfoText は path の情報がまだ表示対象であればパネルに表示する
+func (m *FilesView) setInfoText(path string, text string) {
+	m.Application.QueueUpdateDraw(func() {
+		if m.infoPath != path {
+			return
+		}
+		m.InfoTextView.SetText(text)
+		m.InfoTextView.ScrollToBeginning()
+	})
+}
+
+// loadFileInfo はファイルの情報を情報パネルに表示する
+// content はプレビューのために読み込んだ内容。テキストとして読み込まない場合は nil を渡す
+func (m *FilesView) loadFileInfo(path string, content []byte) {
+	var lines []string
+	add := func(name string, value string) {
+		lines = append(lines, fmt.Sprintf("[yellow]%-11s[-] %s", name+":", value))
+	}
+
+	add("Path", tview.Escape(path))
+
+	info := m.fileInfo(path)
+	if info != nil {
+		add("Size", fmt.Sprintf("%s (%d bytes)", humanSize(info.Size()), info.Size()))
+		add("Mode", info.Mode().String())
+		if !m.IsRevisionMode() {
+			add("Owner", tview.Escape(fileOwner(info)))
+			add("Modified", info.ModTime().Format("2006-01-02 15:04:05"))
+		}
+		if info.Mode()&fs.ModeSymlink != 0 && !m.IsRevisionMode() {
+			if target, err := os.Readlink(path); err == nil {
+				if _, err := os.Stat(path); err != nil {
+					add("Link", tview.Escape(target)+" [red](broken)[-]")
+				} else {
+					add("Link", tview.Escape(target))
+				}
+			}
+		}
+	}
+
+	add("MIME", tview.Escape(detectMIME(path, content)))
+	if content != nil {
+		if c := m.charsetOf(path, content); c != nil {
+			if text, err := c.Decode(content); err == nil {
+				add("Lines", fmt.Sprintf("%d", countLines(text)))
+				add("Line end", describeLineEndings(text))
+			}
+			add("Encoding", c.String())
+		} else {
+			add("Encoding", "binary")
+		}
+	}
+
+	text := strings.Join(lines, "\n")
+	m.setInfoText(path, text+"\n"+fmt.Sprintf("[yellow]%-11s[-] %s", "Commit:", "[blue]Loading..."))
+
+	add("Commit", m.describeLastCommit(path))
+	m.setInfoText(path, strings.Join(lines, "\n"))
+}
+
+// dirEntryCounts はディレクトリの中のエントリを種類ごとに数えたもの
+type dirEntryCounts struct {
+	files    int
+	dirs     int
+	symlinks int
+	// 通常のファイル、ディレクトリ、リンク以外（デバイスやソケットなど）
+	others int
+	hidden int
+}
+
+// countDirEntries はディレクトリの中のエントリを種類ごとに数える
+func countDirEntries(entries []fs.DirEntry) dirEntryCounts {
+	var counts dirEntryCounts
+	for _, entry := range entries {
+		switch {
+		case entry.Type()&fs.ModeSymlink != 0:
+			counts.symlinks++
+		case entry.IsDir():
+			counts.dirs++
+		case entry.Type().IsRegular():
+			counts.files++
+		default:
+			counts.others++
+		}
+		if strings.HasPrefix(entry.Name(), ".") {
+			counts.hidden++
+		}
+	}
+	return counts
+}
+
+// loadDirectoryInfo はディレクトリの中のエントリの数を情報パネルに表示する
+// entries と err はプレビューのためにディレクトリを読み込んだ結果
+func (m *FilesView) loadDirectoryInfo(path string, entries []fs.DirEntry, err error) {
+	if err != nil {
+		m.setInfoText(path, fmt.Sprintf("[red]%s", tview.Escape(err.Error())))
+		return
+	}
+
+	counts := countDirEntries(entries)
+	lines := []string{
+		fmt.Sprintf("[yellow]%-11s[-] %s", "Path:", tview.Escape(path)),
+		fmt.Sprintf("[yellow]%-11s[-] %d (%d files, %d directories, %d symlinks, %d hidden)",
+			"Entries:", len(entries), counts.files+counts.others, counts.dirs, counts.symlinks, counts.hidden),
+	}
+	if info := m.fileInfo(path); info != nil {
+		lines = append(lines, fmt.Sprintf("[yellow]%-11s[-] %s", "Mode:", info.Mode().String()))
+		if !m.IsRevisionMode() {
+			lines = append(lines, fmt.Sprintf("[yellow]%-11s[-] %s", "Owner:", tview.Escape(fileOwner(info))))
+			lines = append(lines, fmt.Sprintf("[yellow]%-11s[-] %s", "Modified:", info.ModTime().Format("2006-01-02 15:04:05")))
+		}
+	}
+	m.setInfoText(path, strings.Join(lines, "\n"))
+}
+
+// fileInfo はファイルの情報を返す。リビジョンの表示中は `git ls-tree` の情報を使う
+func (m *FilesView) fileInfo(path string) fs.FileInfo {
+	if !m.IsRevisionMode() {
+		info, err := os.Lstat(path)
+		if err != nil {
+			log.Printf("Failed to stat %s: %v", path, err)
+			return nil
+		}
+		return info
+	}
+
+	entries, err := m.readDir(filepath.Dir(path))
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		if entry.Name() == filepath.Base(path) {
+			if info, err := entry.Info(); err == nil {
+				return info
+			}
+		}
+	}
+	return nil
+}
+
+// describeLastCommit は path を最後に変更したコミットを 1 行で返す
+func (m *FilesView) describeLastCommit(path string) string {
+	entry, err := git.LastCommit(path, m.revisionHash)
+	if err != nil {
+		log.Printf("Failed to get last commit of %s: %v", path, err)
+		return "-"
+	}
+	if entry == nil {
+		return "(not committed)"
+	}
+	return fmt.Sprintf("[yellow]%s[-] %s %s %s",
+		entry.ShortHash(), entry.Date, tview.Escape(entry.Author), tview.Escape(entry.Subject))
+}
+
+// detectMIME は内容から MIME タイプを推測する。内容から分からない場合は拡張子を使う
+func detectMIME(path string, content []byte) string {
+	byExt := mime.TypeByExtension(filepath.Ext(path))
+	if content == nil {
+		if byExt == "" {
+			return "-"
+		}
+		return byExt
+	}
+
+	detected := http.DetectContentType(content)
+	// 汎用的な結果しか得られなかった場合は拡張子からの推測を優先する
+	if byExt != "" && (strings.HasPrefix(detected, "text/plain") || detected == "application/octet-stream") {
+		return byExt
+	}
+	return detected
+}
+
+// countLines は行数を返す。最後の行が改行で終わっていなくても 1 行と数える
+func countLines(content []byte) int {
+	if len(content) == 0 {
+		return 0
+	}
+	n := bytes.Count(content, []byte("\n"))
+	if content[len(content)-1] != '\n' {
+		n++
+	}
+	return n
+}
+
+// describeLineEndings は改行コードの種類を返す
+func describeLineEndings(content []byte) string {
+	crlf := bytes.Count(content, []byte("\r\n"))
+	lf := bytes.Count(content, []byte("\n")) - crlf
+	cr := bytes.Count(content, []byte("\r")) - crlf
+
+	var kinds []string
+	if lf > 0 {
+		kinds = append(kinds, "LF")
+	}
+	if crlf > 0 {
+		kinds = append(kinds, "CRLF")
+	}
+	if cr > 0 {
+		kinds = append(kinds, "CR")
+	}
+
+	switch len(kinds) {
+	case 0:
+		return "none"
+	case 1:
+		return kinds[0]
+	default:
+		return "mixed (" + strings.Join(kinds, ", ") + ")"
+	}
+}
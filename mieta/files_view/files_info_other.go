@@ -0,0 +1,10 @@
+//go:build !unix
+
+package files_view
+
+import "io/fs"
+
+// fileOwner は所有者を取得できない環境では "-" を返す
+func fileOwner(info fs.FileInfo) string {
+	return "-"
+}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package files_view
+
+import (
+	"io/fs"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// fileOwner はファイルの所有者とグループを "user:group" の形式で返す
+func fileOwner(info fs.FileInfo) string {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "-"
+	}
+
+	uid := strconv.FormatUint(uint64(stat.Uid), 10)
+	gid := strconv.FormatUint(uint64(stat.Gid), 10)
+
+	owner := uid
+	if u, err := user.LookupId(uid); err == nil {
+		owner = u.Username
+	}
+	group := gid
+	if g, err := user.LookupGroupId(gid); err == nil {
+		group = g.Name
+	}
+	return owner + ":" + group
+}
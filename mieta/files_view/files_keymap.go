@@ -9,45 +9,125 @@ import (
 type FilesViewHandler func(view *FilesView)
 
 var FilesFunctions = map[string]FilesViewHandler{
-	"FilesScrollDown":        FilesScrollDown,
-	"FilesScrollUp":          FilesScrollUp,
-	"FilesQuit":              FilesQuit,
-	"FilesShowHelp":          FilesShowHelp,
-	"FilesMoveUp":            FilesMoveUp,
-	"FilesMoveDown":          FilesMoveDown,
-	"FilesShowSearch":        FilesShowSearch,
-	"FilesEdit":              FilesEdit,
-	"FilesNavigateUp":        FilesNavigateUp,
-	"FilesExpand":            FilesExpand,
-	"FilesScrollPageDown":    FilesScrollPageDown,
-	"FilesDecreaseTreeWidth": FilesDecreaseTreeWidth,
-	"FilesIncreaseTreeWidth": FilesIncreaseTreeWidth,
-	"FilesEnterFindMode":     FilesEnterFindMode,
-	"FilesInlineSearch":      FilesInlineSearch,
-	"FilesFindPrev":          FilesFindPrev,
-	"FilesFindNext":          FilesFindNext,
+	"FilesScrollDown":          FilesScrollDown,
+	"FilesScrollUp":            FilesScrollUp,
+	"FilesQuit":                FilesQuit,
+	"FilesShowHelp":            FilesShowHelp,
+	"FilesMoveUp":              FilesMoveUp,
+	"FilesMoveDown":            FilesMoveDown,
+	"FilesShowSearch":          FilesShowSearch,
+	"FilesEdit":                FilesEdit,
+	"FilesNavigateUp":          FilesNavigateUp,
+	"FilesExpand":              FilesExpand,
+	"FilesScrollPageDown":      FilesScrollPageDown,
+	"FilesDecreaseTreeWidth":   FilesDecreaseTreeWidth,
+	"FilesIncreaseTreeWidth":   FilesIncreaseTreeWidth,
+	"FilesEnterFindMode":       FilesEnterFindMode,
+	"FilesInlineSearch":        FilesInlineSearch,
+	"FilesFindPrev":            FilesFindPrev,
+	"FilesFindNext":            FilesFindNext,
+	"FilesToggleDiff":          FilesToggleDiff,
+	"FilesToggleBlame":         FilesToggleBlame,
+	"FilesShowHistory":         FilesShowHistory,
+	"FilesCreateFile":          FilesCreateFile,
+	"FilesCreateDirectory":     FilesCreateDirectory,
+	"FilesRename":              FilesRename,
+	"FilesCopy":                FilesCopy,
+	"FilesMove":                FilesMove,
+	"FilesDelete":              FilesDelete,
+	"FilesToggleMark":          FilesToggleMark,
+	"FilesMarkSiblings":        FilesMarkSiblings,
+	"FilesInvertMarks":         FilesInvertMarks,
+	"FilesClearMarks":          FilesClearMarks,
+	"FilesEditMarked":          FilesEditMarked,
+	"FilesCopyMarkedPaths":     FilesCopyMarkedPaths,
+	"FilesDeleteMarked":        FilesDeleteMarked,
+	"FilesRunCommand":          FilesRunCommand,
+	"FilesYankAbsolutePath":    FilesYankAbsolutePath,
+	"FilesYankRelativePath":    FilesYankRelativePath,
+	"FilesYankPathWithLine":    FilesYankPathWithLine,
+	"FilesYankPreviewLines":    FilesYankPreviewLines,
+	"FilesAddBookmark":         FilesAddBookmark,
+	"FilesShowBookmarks":       FilesShowBookmarks,
+	"FilesSetRoot":             FilesSetRoot,
+	"FilesRootUp":              FilesRootUp,
+	"FilesRootBack":            FilesRootBack,
+	"FilesCycleSortMode":       FilesCycleSortMode,
+	"FilesToggleSortReverse":   FilesToggleSortReverse,
+	"FilesToggleDirsFirst":     FilesToggleDirsFirst,
+	"FilesToggleHidden":        FilesToggleHidden,
+	"FilesToggleHideIgnored":   FilesToggleHideIgnored,
+	"FilesToggleDetail":        FilesToggleDetail,
+	"FilesToggleInfo":          FilesToggleInfo,
+	"FilesFuzzyFind":           FilesFuzzyFind,
+	"FilesEnterFilterMode":     FilesEnterFilterMode,
+	"FilesExpandRecursively":   FilesExpandRecursively,
+	"FilesExpandAll":           FilesExpandAll,
+	"FilesCollapseRecursively": FilesCollapseRecursively,
+	"FilesToggleMarkdown":      FilesToggleMarkdown,
+	"FilesHexGoToOffset":       FilesHexGoToOffset,
 }
 
 var DefaultKeyMap = map[string]string{
-	"j":     "FilesScrollDown",
-	"k":     "FilesScrollUp",
-	"q":     "FilesQuit",
-	"?":     "FilesShowHelp",
-	"w":     "FilesMoveUp",
-	"s":     "FilesMoveDown",
-	"S":     "FilesShowSearch",
-	"e":     "FilesEdit",
-	"a":     "FilesNavigateUp",
-	"left":  "FilesNavigateUp",
-	"d":     "FilesExpand",
-	"right": "FilesExpand",
-	" ":     "FilesScrollPageDown",
-	"H":     "FilesDecreaseTreeWidth",
-	"L":     "FilesIncreaseTreeWidth",
-	"f":     "FilesEnterFindMode",
-	"/":     "FilesInlineSearch",
-	"n":     "FilesFindNext",
-	"N":     "FilesFindPrev",
+	"j":      "FilesScrollDown",
+	"k":      "FilesScrollUp",
+	"q":      "FilesQuit",
+	"?":      "FilesShowHelp",
+	"w":      "FilesMoveUp",
+	"s":      "FilesMoveDown",
+	"S":      "FilesShowSearch",
+	"e":      "FilesEdit",
+	"a":      "FilesNavigateUp",
+	"left":   "FilesNavigateUp",
+	"d":      "FilesExpand",
+	"right":  "FilesExpand",
+	" ":      "FilesScrollPageDown",
+	"H":      "FilesDecreaseTreeWidth",
+	"L":      "FilesIncreaseTreeWidth",
+	"f":      "FilesEnterFindMode",
+	"/":      "FilesInlineSearch",
+	"n":      "FilesFindNext",
+	"N":      "FilesFindPrev",
+	"D":      "FilesToggleDiff",
+	"B":      "FilesToggleBlame",
+	"h":      "FilesShowHistory",
+	"c":      "FilesCreateFile",
+	"+":      "FilesCreateDirectory",
+	"r":      "FilesRename",
+	"p":      "FilesCopy",
+	"m":      "FilesMove",
+	"X":      "FilesDeleteMarked",
+	"Delete": "FilesDelete",
+	"t":      "FilesToggleMark",
+	"T":      "FilesMarkSiblings",
+	"i":      "FilesInvertMarks",
+	"u":      "FilesClearMarks",
+	"E":      "FilesEditMarked",
+	"C":      "FilesCopyMarkedPaths",
+	"!":      "FilesRunCommand",
+	"y":      "FilesYankAbsolutePath",
+	"Y":      "FilesYankRelativePath",
+	"Ctrl-Y": "FilesYankPathWithLine",
+	"Ctrl-K": "FilesYankPreviewLines",
+	"b":      "FilesAddBookmark",
+	"'":      "FilesShowBookmarks",
+	"R":      "FilesSetRoot",
+	"U":      "FilesRootUp",
+	"<":      "FilesRootBack",
+	"o":      "FilesCycleSortMode",
+	"O":      "FilesToggleSortReverse",
+	"Ctrl-O": "FilesToggleDirsFirst",
+	".":      "FilesToggleHidden",
+	"I":      "FilesToggleHideIgnored",
+	"l":      "FilesToggleDetail",
+	"Ctrl-G": "FilesToggleInfo",
+	"Ctrl-P": "FilesFuzzyFind",
+	"F":      "FilesEnterFilterMode",
+	"*":      "FilesExpandRecursively",
+	"#":      "FilesExpandAll",
+	"z":      "FilesCollapseRecursively",
+	"M":      "FilesToggleMarkdown",
+	"g":      "FilesHexGoToOffset",
 }
 
 func GetFilesKeymap(config *config.Config) (map[string]string, map[tcell.Key]FilesViewHandler, map[rune]FilesViewHandler) {
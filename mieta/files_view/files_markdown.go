@@ -0,0 +1,111 @@
+package files_view
+
+import (
+	"bytes"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/alecthomas/chroma/quick"
+	"github.com/rivo/tview"
+	"github.com/tokuhirom/mieta/mieta/markdown"
+	"github.com/tokuhirom/mieta/mieta/state"
+	"log"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// previewModesFileName は拡張子ごとのプレビューの表示方法を保存するファイルの名前
+const previewModesFileName = "preview_modes.json"
+
+// 拡張子ごとのプレビューの表示方法
+const (
+	previewModeRendered = "rendered"
+	previewModeSource   = "source"
+)
+
+// Markdown として表示するファイルの拡張子
+var markdownExtensions = []string{".md", ".markdown", ".mdown", ".mkd"}
+
+// isMarkdownFile は Markdown として表示できるファイルかを返す
+func isMarkdownFile(path string) bool {
+	return slices.Contains(markdownExtensions, strings.ToLower(filepath.Ext(path)))
+}
+
+// loadPreviewModes は保存しておいた拡張子ごとの表示方法を読み込む
+func (m *FilesView) loadPreviewModes() {
+	modes := map[string]string{}
+	if err := state.Load(previewModesFileName, &modes); err != nil {
+		log.Printf("Failed to load preview modes: %v", err)
+	}
+
+	m.previewModesMutex.Lock()
+	defer m.previewModesMutex.Unlock()
+	m.previewModes = modes
+}
+
+// renderMarkdown は path を Markdown として整形して表示するかを返す。保存されていなければ整形する
+func (m *FilesView) renderMarkdown(path string) bool {
+	if !isMarkdownFile(path) {
+		return false
+	}
+
+	m.previewModesMutex.Lock()
+	defer m.previewModesMutex.Unlock()
+	return m.previewModes[strings.ToLower(filepath.Ext(path))] != previewModeSource
+}
+
+// showingRenderedMarkdown は path を整形した Markdown としてプレビューに表示しているかを返す
+// diff や blame を表示している場合は整形しない
+func (m *FilesView) showingRenderedMarkdown(path string) bool {
+	if !m.renderMarkdown(path) || m.DiffMode != DiffOff {
+		return false
+	}
+	return !m.BlameMode || !m.canBlame(path)
+}
+
+// toggleMarkdownRendering は選択中のファイルと同じ拡張子のファイルを、整形して表示するかソースのまま表示するかを切り替える
+func (m *FilesView) toggleMarkdownRendering() {
+	_, fileNode := m.selectedFileNode()
+	if fileNode == nil || fileNode.IsDir || !isMarkdownFile(fileNode.Path) {
+		return
+	}
+
+	mode := previewModeSource
+	if !m.renderMarkdown(fileNode.Path) {
+		mode = previewModeRendered
+	}
+
+	m.previewModesMutex.Lock()
+	m.previewModes[strings.ToLower(filepath.Ext(fileNode.Path))] = mode
+	modes := make(map[string]string, len(m.previewModes))
+	for ext, mode := range m.previewModes {
+		modes[ext] = mode
+	}
+	m.previewModesMutex.Unlock()
+
+	if err := state.Save(previewModesFileName, modes); err != nil {
+		log.Printf("Failed to save preview modes: %v", err)
+	}
+	m.reloadPreview()
+}
+
+// formatText はプレビューに表示するためにテキストを整形する
+// Markdown は設定に応じて整形し、それ以外はシンタックスハイライトする
+func (m *FilesView) formatText(path string, content string) string {
+	if m.renderMarkdown(path) {
+		return markdown.Render(content, m.highlightCode)
+	}
+	return HighlightContent(m.Config, path, content)
+}
+
+// highlightCode は Markdown のコードブロックを言語名でハイライトする。知らない言語ならそのまま返す
+func (m *FilesView) highlightCode(lang string, code string) string {
+	if lexers.Get(lang) == nil || len(code) > m.Config.HighlightLimit {
+		return tview.Escape(code)
+	}
+
+	var highlighted bytes.Buffer
+	if err := quick.Highlight(&highlighted, code, lang, "terminal", m.Config.ChromaStyle); err != nil {
+		return tview.Escape(code)
+	}
+	return tview.TranslateANSI(highlighted.String())
+}
@@ -0,0 +1,238 @@
+package files_view
+
+import (
+	"fmt"
+	"github.com/rivo/tview"
+	"github.com/tokuhirom/mieta/mieta"
+	"github.com/tokuhirom/mieta/mieta/clipboard"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// markPrefix はマークされたノードのラベルの先頭に付ける記号
+const markPrefix = "[yellow::b]✔[-::-]"
+
+// isMarked はパスがマークされているかを返す
+func (m *FilesView) isMarked(path string) bool {
+	return m.marked[path]
+}
+
+// setMarked はマークの状態を変更してノードの表示を更新する
+func (m *FilesView) setMarked(node *tview.TreeNode, marked bool) {
+	fileNode, ok := node.GetReference().(*FileNode)
+	if !ok || fileNode.Path == m.RootDir {
+		return
+	}
+
+	if marked {
+		m.marked[fileNode.Path] = true
+	} else {
+		delete(m.marked, fileNode.Path)
+	}
+	m.decorateNode(node)
+}
+
+// unmarkPath は path とその配下のマークを外す
+func (m *FilesView) unmarkPath(path string) {
+	for markedPath := range m.marked {
+		if markedPath == path || strings.HasPrefix(markedPath, path+string(filepath.Separator)) {
+			delete(m.marked, markedPath)
+		}
+	}
+}
+
+// siblingNodes は node と同じディレクトリにあるノードを返す
+func (m *FilesView) siblingNodes(node *tview.TreeNode) []*tview.TreeNode {
+	path := m.TreeView.GetPath(node)
+	if len(path) < 2 {
+		return nil
+	}
+
+	var siblings []*tview.TreeNode
+	for _, child := range path[len(path)-2].GetChildren() {
+		if child.GetReference() != nil {
+			siblings = append(siblings, child)
+		}
+	}
+	return siblings
+}
+
+// markedPaths はマークされたパスをソートして返す
+func (m *FilesView) markedPaths() []string {
+	paths := make([]string, 0, len(m.marked))
+	for path := range m.marked {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// targetPaths は一括操作の対象を返す。マークがあればマークされたパス、なければ選択中のパス
+func (m *FilesView) targetPaths() []string {
+	if len(m.marked) > 0 {
+		return m.markedPaths()
+	}
+	if _, fileNode := m.selectedFileNode(); fileNode != nil {
+		return []string{fileNode.Path}
+	}
+	return nil
+}
+
+// toggleMark は選択中のノードのマークを切り替えて、次のノードに移動する
+func (m *FilesView) toggleMark() {
+	node, fileNode := m.selectedFileNode()
+	if fileNode == nil {
+		return
+	}
+
+	m.setMarked(node, !m.isMarked(fileNode.Path))
+	m.TreeView.Move(1)
+}
+
+// markSiblings は選択中のノードと同じディレクトリのノードをすべてマークする
+func (m *FilesView) markSiblings() {
+	node := m.TreeView.GetCurrentNode()
+	if node == nil {
+		return
+	}
+
+	for _, sibling := range m.siblingNodes(node) {
+		m.setMarked(sibling, true)
+	}
+}
+
+// invertMarks は選択中のノードと同じディレクトリのノードのマークを反転する
+func (m *FilesView) invertMarks() {
+	node := m.TreeView.GetCurrentNode()
+	if node == nil {
+		return
+	}
+
+	for _, sibling := range m.siblingNodes(node) {
+		m.setMarked(sibling, !m.isMarked(sibling.GetReference().(*FileNode).Path))
+	}
+}
+
+// clearMarks はすべてのマークを外す
+func (m *FilesView) clearMarks() {
+	m.marked = make(map[string]bool)
+	m.refreshNodeStyles(m.TreeView.GetRoot())
+}
+
+// editTargets はマークされたファイル（なければ選択中のファイル）をまとめてエディタで開く
+func (m *FilesView) editTargets() {
+	if m.IsRevisionMode() {
+		log.Printf("Editing is disabled while browsing revision %s", m.Revision)
+		return
+	}
+
+	var files []string
+	for _, path := range m.targetPaths() {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			files = append(files, path)
+		}
+	}
+	if len(files) == 0 {
+		return
+	}
+
+	mieta.OpenFilesInEditor(m.Application, m.Config, files)
+}
+
+// copyTargetPaths はマークされたパス（なければ選択中のパス）を改行区切りでクリップボードにコピーする
+func (m *FilesView) copyTargetPaths() {
+	paths := m.targetPaths()
+	if len(paths) == 0 {
+		return
+	}
+
+	if err := clipboard.Copy(strings.Join(paths, "\n")); err != nil {
+		m.showMessageDialog(fmt.Sprintf("Failed to copy to clipboard: %s", tview.Escape(err.Error())))
+		return
+	}
+	log.Printf("Copied %d paths to clipboard", len(paths))
+}
+
+// deleteTargets はマークされたパスを確認してから削除する。マークがなければ選択中のノードを削除する
+func (m *FilesView) deleteTargets() {
+	if len(m.marked) == 0 {
+		m.deleteSelected()
+		return
+	}
+	if !m.checkWritable() {
+		return
+	}
+
+	paths := m.markedPaths()
+	message := fmt.Sprintf("Delete %d marked items?\n\n%s", len(paths), tview.Escape(m.summarizePaths(paths)))
+	m.showConfirmDialog(message, "Delete", func() {
+		m.removePaths(paths, nil)
+	})
+}
+
+// summarizePaths はダイアログに表示するためにパスの一覧を短くまとめる
+func (m *FilesView) summarizePaths(paths []string) string {
+	const maxLines = 5
+
+	var lines []string
+	for i, path := range paths {
+		if i == maxLines {
+			lines = append(lines, fmt.Sprintf("... and %d more", len(paths)-maxLines))
+			break
+		}
+		lines = append(lines, m.relativePath(path))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// runCustomCommand は設定ファイルで定義したコマンドを選んで、マークされたパスを渡して実行する
+func (m *FilesView) runCustomCommand() {
+	if len(m.Config.Commands) == 0 {
+		m.showMessageDialog("No commands are configured.\nAdd them to the [commands] section of config.toml.")
+		return
+	}
+
+	names := make([]string, 0, len(m.Config.Commands))
+	for name := range m.Config.Commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	list := tview.NewList().ShowSecondaryText(true)
+	list.SetBorder(true)
+	list.SetTitle(" Run command ")
+	for _, name := range names {
+		list.AddItem(tview.Escape(name), tview.Escape(m.Config.Commands[name]), 0, nil)
+	}
+	list.SetSelectedFunc(func(index int, mainText string, secondaryText string, shortcut rune) {
+		m.closeDialog()
+		m.runCommandLine(m.Config.Commands[names[index]])
+	})
+	list.SetDoneFunc(func() {
+		m.closeDialog()
+	})
+
+	m.showDialog(list, 60, min(len(names)*2+2, 20))
+}
+
+// runCommandLine は {files} を対象のパスに置き換えてコマンドを実行する
+// {files} がなければ末尾にパスを追加する
+func (m *FilesView) runCommandLine(commandLine string) {
+	paths := m.targetPaths()
+	quoted := make([]string, len(paths))
+	for i, path := range paths {
+		quoted[i] = mieta.ShellQuote(path)
+	}
+	files := strings.Join(quoted, " ")
+
+	if strings.Contains(commandLine, "{files}") {
+		commandLine = strings.ReplaceAll(commandLine, "{files}", files)
+	} else {
+		commandLine += " " + files
+	}
+
+	mieta.RunShellCommand(m.Application, m.RootDir, commandLine)
+}
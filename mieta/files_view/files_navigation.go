@@ -0,0 +1,208 @@
+package files_view
+
+import (
+	"fmt"
+	"github.com/rivo/tview"
+	"github.com/tokuhirom/mieta/mieta/bookmark"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+// isUnder は path が dir 自身か dir の中にあるかを返す
+func isUnder(path string, dir string) bool {
+	relPath, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
+
+// maxRootHistory は戻れるルートディレクトリの数
+const maxRootHistory = 50
+
+// rootHistoryEntry は以前のルートディレクトリと、そのとき選択していたパス
+type rootHistoryEntry struct {
+	rootDir      string
+	selectedPath string
+}
+
+// SetRootDir はツリーのルートを dir に変更して読み込み直す
+// 変更前のルートは戻れるように履歴に積んでおく
+func (m *FilesView) SetRootDir(dir string) {
+	dir = filepath.Clean(dir)
+	if dir == m.RootDir {
+		return
+	}
+
+	entry := rootHistoryEntry{rootDir: m.RootDir}
+	if _, fileNode := m.selectedFileNode(); fileNode != nil {
+		entry.selectedPath = fileNode.Path
+	}
+	m.rootHistory = append(m.rootHistory, entry)
+	if len(m.rootHistory) > maxRootHistory {
+		m.rootHistory = m.rootHistory[len(m.rootHistory)-maxRootHistory:]
+	}
+
+	m.setRootDir(dir)
+}
+
+// setRootDir は履歴に積まずにルートを変更する
+func (m *FilesView) setRootDir(dir string) {
+	log.Printf("Changing root directory: %s", dir)
+	m.RootDir = dir
+	m.rebuildTree()
+	m.showPreview(m.TreeView.GetRoot())
+
+	if !m.IsRevisionMode() {
+		go m.refreshGitStatus()
+	}
+
+	if m.OnRootChanged != nil {
+		m.OnRootChanged(dir)
+	}
+}
+
+// rebuildTree は RootDir のノードを作り直して、中身を読み込み始める
+// 古いツリーのディレクトリの監視はやめて、新しいツリーで読み込んだディレクトリを監視し直す
+func (m *FilesView) rebuildTree() {
+	m.cancelExpansion()
+	m.unwatchTree()
+	root := newRootNode(m.RootDir, m.Revision)
+	m.TreeView.SetRoot(root).SetCurrentNode(root)
+	m.invalidateFinderIndex()
+	if err := m.loadDirectoryContents(root, m.RootDir); err != nil {
+		log.Printf("Error loading root directory: %v", err)
+	}
+	m.refilterTree()
+}
+
+// reloadTree はツリーを読み込み直し、展開していたディレクトリと選択していたノードを復元する
+// 表示するファイルの条件が変わったときに使う
+func (m *FilesView) reloadTree() {
+	dirs := m.expandedDirs()
+	_, selected := m.selectedFileNode()
+
+	m.rebuildTree()
+	m.expandDirs(dirs)
+	if selected != nil {
+		m.revealPath(selected.Path)
+	}
+}
+
+// setRootToSelected は選択中のディレクトリ（ファイルならその親ディレクトリ）をルートにする
+func (m *FilesView) setRootToSelected() {
+	_, fileNode := m.selectedFileNode()
+	if fileNode == nil {
+		return
+	}
+
+	dir := fileNode.Path
+	if !fileNode.IsDir {
+		dir = filepath.Dir(dir)
+	}
+	m.SetRootDir(dir)
+}
+
+// moveRootUp はルートを親ディレクトリに変更して、元のルートを選択する
+func (m *FilesView) moveRootUp() {
+	parent := filepath.Dir(m.RootDir)
+	if parent == m.RootDir {
+		return
+	}
+
+	previousRoot := m.RootDir
+	m.SetRootDir(parent)
+	m.revealPath(previousRoot)
+}
+
+// backRoot は一つ前のルートに戻り、そのとき選択していたノードを選択する
+func (m *FilesView) backRoot() {
+	if len(m.rootHistory) == 0 {
+		return
+	}
+
+	entry := m.rootHistory[len(m.rootHistory)-1]
+	m.rootHistory = m.rootHistory[:len(m.rootHistory)-1]
+
+	m.setRootDir(entry.rootDir)
+	if entry.selectedPath != "" && isUnder(entry.selectedPath, entry.rootDir) {
+		m.revealPath(entry.selectedPath)
+	}
+}
+
+// walkToPath は path までのディレクトリを順に非同期で読み込み、path のノードを fn に渡す
+// path が見つからない場合は、たどれたところまでで一番深いノードを found = false で渡す
+func (m *FilesView) walkToPath(path string, fn func(node *tview.TreeNode, found bool)) {
+	var walk func(node *tview.TreeNode)
+	walk = func(node *tview.TreeNode) {
+		m.afterLoaded(node, func() {
+			for _, child := range node.GetChildren() {
+				fileNode, ok := child.GetReference().(*FileNode)
+				if !ok {
+					continue
+				}
+				if fileNode.Path == path {
+					fn(child, true)
+					return
+				}
+				if fileNode.IsDir && isUnder(path, fileNode.Path) {
+					walk(child)
+					return
+				}
+			}
+
+			log.Printf("Cannot find %s in the tree", path)
+			fn(node, false)
+		})
+	}
+
+	root := m.TreeView.GetRoot()
+	if path == m.RootDir {
+		fn(root, true)
+		return
+	}
+	walk(root)
+}
+
+// revealPath は path までのディレクトリを展開して path のノードを選択する
+func (m *FilesView) revealPath(path string) {
+	m.walkToPath(path, func(node *tview.TreeNode, found bool) {
+		m.selectNode(node)
+	})
+}
+
+// ShowPath は path をツリーで選択する
+// RootDir の外にある場合は、path のディレクトリ（ファイルなら親ディレクトリ）をルートにする
+func (m *FilesView) ShowPath(path string, isDir bool) {
+	if !isUnder(path, m.RootDir) {
+		dir := path
+		if !isDir {
+			dir = filepath.Dir(path)
+		}
+		m.SetRootDir(dir)
+	}
+	m.revealPath(path)
+}
+
+// addBookmark は選択中のノードに名前を付けてブックマークに追加する
+func (m *FilesView) addBookmark() {
+	_, fileNode := m.selectedFileNode()
+	if fileNode == nil {
+		return
+	}
+
+	title := "Bookmark " + tview.Escape(m.relativePath(fileNode.Path)) + " as"
+	m.showInputDialog(title, filepath.Base(fileNode.Path), func(name string) {
+		b := bookmark.Bookmark{
+			Name:  strings.TrimSpace(name),
+			Path:  fileNode.Path,
+			IsDir: fileNode.IsDir,
+		}
+		if err := bookmark.Add(b); err != nil {
+			m.showMessageDialog(fmt.Sprintf("Failed to save bookmark: %s", tview.Escape(err.Error())))
+			return
+		}
+		log.Printf("Bookmarked %s as %s", b.Path, b.Name)
+	})
+}
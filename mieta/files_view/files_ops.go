@@ -0,0 +1,373 @@
+package files_view
+
+import (
+	"fmt"
+	"github.com/rivo/tview"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// targetDirectory はファイルを作成する先のディレクトリを返す
+// ディレクトリが選択されていればそのディレクトリ、ファイルならその親ディレクトリ
+func (m *FilesView) targetDirectory() string {
+	_, fileNode := m.selectedFileNode()
+	if fileNode == nil {
+		return m.RootDir
+	}
+	if fileNode.IsDir {
+		return fileNode.Path
+	}
+	return filepath.Dir(fileNode.Path)
+}
+
+// relativePath は RootDir からの相対パスを返す
+func (m *FilesView) relativePath(path string) string {
+	relPath, err := filepath.Rel(m.RootDir, path)
+	if err != nil {
+		return path
+	}
+	return relPath
+}
+
+// resolveInputPath は入力されたパスを base からの相対パスとして解決する
+func resolveInputPath(base string, input string) string {
+	input = strings.TrimSpace(input)
+	if filepath.IsAbs(input) {
+		return filepath.Clean(input)
+	}
+	return filepath.Join(base, input)
+}
+
+// checkWritable はファイル操作ができる状態かを確認する
+func (m *FilesView) checkWritable() bool {
+	if m.IsRevisionMode() {
+		m.showMessageDialog("Files cannot be modified while browsing a revision")
+		return false
+	}
+	return true
+}
+
+// createFile は選択中のディレクトリに新しいファイルを作成する
+func (m *FilesView) createFile() {
+	if !m.checkWritable() {
+		return
+	}
+
+	dir := m.targetDirectory()
+	m.showInputDialog("New file in "+tview.Escape(m.relativePath(dir)), "", func(name string) {
+		path := resolveInputPath(dir, name)
+		if _, err := os.Lstat(path); err == nil {
+			m.showMessageDialog(fmt.Sprintf("Already exists: %s", tview.Escape(m.relativePath(path))))
+			return
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			m.showOperationError("create", path, err)
+			return
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err != nil {
+			m.showOperationError("create", path, err)
+			return
+		}
+		if err := file.Close(); err != nil {
+			log.Printf("Failed to close %s: %v", path, err)
+		}
+
+		log.Printf("Created file: %s", path)
+		m.syncPathToTree(path)
+	})
+}
+
+// createDirectory は選択中のディレクトリに新しいディレクトリを作成する
+func (m *FilesView) createDirectory() {
+	if !m.checkWritable() {
+		return
+	}
+
+	dir := m.targetDirectory()
+	m.showInputDialog("New directory in "+tview.Escape(m.relativePath(dir)), "", func(name string) {
+		path := resolveInputPath(dir, name)
+		if _, err := os.Lstat(path); err == nil {
+			m.showMessageDialog(fmt.Sprintf("Already exists: %s", tview.Escape(m.relativePath(path))))
+			return
+		}
+
+		if err := os.MkdirAll(path, 0755); err != nil {
+			m.showOperationError("create", path, err)
+			return
+		}
+
+		log.Printf("Created directory: %s", path)
+		m.syncPathToTree(path)
+	})
+}
+
+// renameSelected は選択中のファイル/ディレクトリの名前を変更する
+func (m *FilesView) renameSelected() {
+	if !m.checkWritable() {
+		return
+	}
+
+	_, fileNode := m.selectedFileNode()
+	if fileNode == nil || fileNode.Path == m.RootDir {
+		return
+	}
+
+	src := fileNode.Path
+	m.showInputDialog("Rename "+tview.Escape(m.relativePath(src)), filepath.Base(src), func(name string) {
+		dst := resolveInputPath(filepath.Dir(src), name)
+		m.movePath(src, dst, "rename")
+	})
+}
+
+// moveSelected は選択中のファイル/ディレクトリを移動する
+func (m *FilesView) moveSelected() {
+	if !m.checkWritable() {
+		return
+	}
+
+	_, fileNode := m.selectedFileNode()
+	if fileNode == nil || fileNode.Path == m.RootDir {
+		return
+	}
+
+	src := fileNode.Path
+	m.showInputDialog("Move "+tview.Escape(m.relativePath(src))+" to", m.relativePath(src), func(input string) {
+		m.movePath(src, m.destinationPath(src, input), "move")
+	})
+}
+
+// copySelected は選択中のファイル/ディレクトリをコピーする
+func (m *FilesView) copySelected() {
+	if !m.checkWritable() {
+		return
+	}
+
+	_, fileNode := m.selectedFileNode()
+	if fileNode == nil || fileNode.Path == m.RootDir {
+		return
+	}
+
+	src := fileNode.Path
+	m.showInputDialog("Copy "+tview.Escape(m.relativePath(src))+" to", m.relativePath(src), func(input string) {
+		dst := m.destinationPath(src, input)
+		if strings.HasPrefix(dst, src+string(filepath.Separator)) {
+			m.showMessageDialog("Cannot copy a directory into itself")
+			return
+		}
+		if _, err := os.Lstat(dst); err == nil {
+			m.showMessageDialog(fmt.Sprintf("Already exists: %s", tview.Escape(m.relativePath(dst))))
+			return
+		}
+
+		if err := copyPath(src, dst); err != nil {
+			m.showOperationError("copy", src, err)
+			return
+		}
+
+		log.Printf("Copied %s to %s", src, dst)
+		m.syncPathToTree(dst)
+	})
+}
+
+// deleteSelected は確認してから選択中のファイル/ディレクトリを削除する
+func (m *FilesView) deleteSelected() {
+	if !m.checkWritable() {
+		return
+	}
+
+	node, fileNode := m.selectedFileNode()
+	if fileNode == nil || fileNode.Path == m.RootDir {
+		return
+	}
+
+	path := fileNode.Path
+	message := fmt.Sprintf("Delete %s?", tview.Escape(m.relativePath(path)))
+	if fileNode.IsDir {
+		message = fmt.Sprintf("Delete directory %s and all of its contents?", tview.Escape(m.relativePath(path)))
+	}
+	m.showConfirmDialog(message, "Delete", func() {
+		// 削除後は近くのノードを選択する
+		next := m.neighborNode(node)
+
+		m.removePaths([]string{path}, func() {
+			if next != nil {
+				m.selectNode(next)
+			}
+		})
+	})
+}
+
+// removePaths は paths を順に削除して、ツリーからノードを取り除く。すべて削除できたら done を呼ぶ
+// 大きなディレクトリの削除で UI が止まらないように、削除は別の goroutine で行う
+func (m *FilesView) removePaths(paths []string, done func()) {
+	go func() {
+		for _, path := range paths {
+			err := os.RemoveAll(path)
+			m.Application.QueueUpdateDraw(func() {
+				if err != nil {
+					m.showOperationError("delete", path, err)
+					return
+				}
+				log.Printf("Deleted: %s", path)
+				m.removeNodeForPath(path)
+			})
+			if err != nil {
+				return
+			}
+		}
+
+		if done != nil {
+			m.Application.QueueUpdateDraw(done)
+		}
+	}()
+}
+
+// destinationPath はコピー/移動先のパスを解決する
+// 既存のディレクトリが指定された場合はその中に同じ名前で置く
+func (m *FilesView) destinationPath(src string, input string) string {
+	dst := resolveInputPath(m.RootDir, input)
+	if info, err := os.Stat(dst); err == nil && info.IsDir() && dst != src {
+		return filepath.Join(dst, filepath.Base(src))
+	}
+	return dst
+}
+
+// movePath は src を dst に移動してツリーを更新する
+func (m *FilesView) movePath(src string, dst string, operation string) {
+	if src == dst {
+		return
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		m.showMessageDialog(fmt.Sprintf("Already exists: %s", tview.Escape(m.relativePath(dst))))
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		m.showOperationError(operation, src, err)
+		return
+	}
+	if err := os.Rename(src, dst); err != nil {
+		m.showOperationError(operation, src, err)
+		return
+	}
+
+	log.Printf("Moved %s to %s", src, dst)
+	m.removeNodeForPath(src)
+	m.syncPathToTree(dst)
+}
+
+// showOperationError はファイル操作のエラーを表示する
+func (m *FilesView) showOperationError(operation string, path string, err error) {
+	log.Printf("Failed to %s %s: %v", operation, path, err)
+	m.showMessageDialog(fmt.Sprintf("Failed to %s %s:\n%s",
+		operation, tview.Escape(m.relativePath(path)), tview.Escape(err.Error())))
+}
+
+// syncPathToTree は path とまだツリーにない親ディレクトリのノードを追加し、
+// 表示できた一番深いノードを選択する
+func (m *FilesView) syncPathToTree(path string) {
+	var ancestors []string
+	for p := path; p != m.RootDir && isUnder(p, m.RootDir); p = filepath.Dir(p) {
+		ancestors = append([]string{p}, ancestors...)
+	}
+
+	var deepest *tview.TreeNode
+	for _, p := range ancestors {
+		node := m.findNodeByPath(m.TreeView.GetRoot(), p)
+		if node == nil {
+			info, err := os.Lstat(p)
+			if err != nil {
+				break
+			}
+			node = m.addNodeForPath(p, info)
+			if node == nil {
+				// 親ディレクトリがまだ読み込まれていない
+				break
+			}
+		}
+		deepest = node
+	}
+
+	if deepest != nil {
+		m.selectNode(deepest)
+	}
+}
+
+// neighborNode は node が削除されたときに代わりに選択するノードを返す
+func (m *FilesView) neighborNode(node *tview.TreeNode) *tview.TreeNode {
+	path := m.TreeView.GetPath(node)
+	if len(path) < 2 {
+		return nil
+	}
+
+	parent := path[len(path)-2]
+	siblings := parent.GetChildren()
+	for i, sibling := range siblings {
+		if sibling != node {
+			continue
+		}
+		if i+1 < len(siblings) {
+			return siblings[i+1]
+		}
+		if i > 0 {
+			return siblings[i-1]
+		}
+	}
+	return parent
+}
+
+// copyPath はファイルまたはディレクトリを再帰的にコピーする
+func copyPath(src string, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	case info.IsDir():
+		if err := os.MkdirAll(dst, info.Mode().Perm()); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := copyPath(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return copyFile(src, dst, info.Mode().Perm())
+	}
+}
+
+func copyFile(src string, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
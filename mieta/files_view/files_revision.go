@@ -0,0 +1,53 @@
+package files_view
+
+import (
+	"bytes"
+	"github.com/rivo/tview"
+	"github.com/tokuhirom/mieta/mieta/git"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// IsRevisionMode はワーキングツリーではなく git のリビジョンを表示しているかを返す
+func (m *FilesView) IsRevisionMode() bool {
+	return m.Revision != ""
+}
+
+// readDir はディレクトリのエントリを返す
+// リビジョンを表示している場合は `git ls-tree` から読み込む
+func (m *FilesView) readDir(path string) ([]fs.DirEntry, error) {
+	if m.IsRevisionMode() {
+		return git.ListTree(path, m.revisionHash)
+	}
+	return os.ReadDir(path)
+}
+
+// readFile はファイルの内容を返す
+// リビジョンを表示している場合は `git show rev:path` から読み込む
+func (m *FilesView) readFile(path string) ([]byte, error) {
+	if m.IsRevisionMode() {
+		return git.ShowFileAtRevision(path, m.revisionHash)
+	}
+	return os.ReadFile(path)
+}
+
+// openFile はファイルを読み込み用に開く
+func (m *FilesView) openFile(path string) (io.ReadCloser, error) {
+	if m.IsRevisionMode() {
+		content, err := m.readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(content)), nil
+	}
+	return os.Open(path)
+}
+
+// previewTitle はプレビューのタイトルを返す
+func (m *FilesView) previewTitle(path string) string {
+	if m.IsRevisionMode() {
+		return path + " @ " + tview.Escape(m.Revision)
+	}
+	return path
+}
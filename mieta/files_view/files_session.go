@@ -0,0 +1,73 @@
+package files_view
+
+import (
+	"github.com/rivo/tview"
+	"github.com/tokuhirom/mieta/mieta/session"
+	"log"
+)
+
+// SaveSession はツリーとプレビューの状態を s に書き込む
+func (m *FilesView) SaveSession(s *session.Session) {
+	s.ExpandedDirs = m.expandedDirs()
+	if _, fileNode := m.selectedFileNode(); fileNode != nil {
+		s.SelectedPath = fileNode.Path
+	}
+	s.PreviewScroll, _ = m.PreviewTextView.GetScrollOffset()
+	// 描画された幅は最初の描画まで 0 なので、設定した幅を保存する
+	s.TreeWidth = m.treeWidth
+}
+
+// RestoreSession は s の状態を復元する
+// ディレクトリの読み込みは非同期なので、読み込みが終わったものから展開される
+func (m *FilesView) RestoreSession(s *session.Session) {
+	if s.TreeWidth > 0 {
+		m.setTreeWidth(s.TreeWidth)
+	}
+
+	m.expandDirs(s.ExpandedDirs)
+
+	if s.SelectedPath != "" && isUnder(s.SelectedPath, m.RootDir) {
+		m.walkToPath(s.SelectedPath, func(node *tview.TreeNode, found bool) {
+			if !found {
+				return
+			}
+			log.Printf("Restoring selection: %s", s.SelectedPath)
+			m.restoreScrollPath = s.SelectedPath
+			m.restoreScrollRow = s.PreviewScroll
+			m.selectNode(node)
+		})
+	}
+}
+
+// expandedDirs は展開して中身を表示しているディレクトリのパスを返す
+func (m *FilesView) expandedDirs() []string {
+	var dirs []string
+	var collect func(node *tview.TreeNode)
+	collect = func(node *tview.TreeNode) {
+		for _, child := range node.GetChildren() {
+			fileNode, ok := child.GetReference().(*FileNode)
+			if !ok || !fileNode.IsDir || !fileNode.loaded || !child.IsExpanded() {
+				continue
+			}
+			dirs = append(dirs, fileNode.Path)
+			collect(child)
+		}
+	}
+	collect(m.TreeView.GetRoot())
+	return dirs
+}
+
+// expandDirs は dirs のディレクトリを非同期に読み込んで展開する
+func (m *FilesView) expandDirs(dirs []string) {
+	for _, dir := range dirs {
+		if !isUnder(dir, m.RootDir) {
+			continue
+		}
+		m.walkToPath(dir, func(node *tview.TreeNode, found bool) {
+			if found {
+				node.Expand()
+				m.afterLoaded(node, func() {})
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package files_view
+
+import (
+	"cmp"
+	"fmt"
+	"github.com/rivo/tview"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// SortMode はツリーの並び順
+type SortMode int
+
+const (
+	// SortByName はファイル名のバイト順
+	SortByName SortMode = iota
+	// SortByNaturalName は数字を数値として比較するファイル名順（file2 < file10）
+	SortByNaturalName
+	// SortByModTime は更新日時の新しい順
+	SortByModTime
+	// SortBySize はサイズの大きい順
+	SortBySize
+	// SortByExtension は拡張子順
+	SortByExtension
+)
+
+var sortModeNames = []string{"name", "natural", "mtime", "size", "extension"}
+
+func (s SortMode) String() string {
+	if int(s) < len(sortModeNames) {
+		return sortModeNames[s]
+	}
+	return fmt.Sprintf("SortMode(%d)", int(s))
+}
+
+// ParseSortMode は設定ファイルに書かれた並び順の名前を SortMode に変換する
+func ParseSortMode(name string) (SortMode, error) {
+	if name == "" {
+		return SortByName, nil
+	}
+	for i, modeName := range sortModeNames {
+		if strings.EqualFold(name, modeName) {
+			return SortMode(i), nil
+		}
+	}
+	return SortByName, fmt.Errorf("unknown sort mode %q (available: %s)", name, strings.Join(sortModeNames, ", "))
+}
+
+// compareFileNodes は現在の並び順で a と b を比較する
+func (m *FilesView) compareFileNodes(a *FileNode, b *FileNode) int {
+	// ディレクトリを先にする設定は逆順にしても変わらない
+	if m.SortDirsFirst && a.IsDir != b.IsDir {
+		if a.IsDir {
+			return -1
+		}
+		return 1
+	}
+
+	c := compareBySortMode(m.SortMode, a, b)
+	if m.SortReverse {
+		c = -c
+	}
+	return c
+}
+
+// compareBySortMode は mode で a と b を比較する。同じ場合はファイル名で比較する
+func compareBySortMode(mode SortMode, a *FileNode, b *FileNode) int {
+	nameA := filepath.Base(a.Path)
+	nameB := filepath.Base(b.Path)
+
+	var c int
+	switch mode {
+	case SortByNaturalName:
+		c = naturalCompare(nameA, nameB)
+	case SortByModTime:
+		c = b.modTime().Compare(a.modTime())
+	case SortBySize:
+		c = cmp.Compare(b.size(), a.size())
+	case SortByExtension:
+		c = strings.Compare(strings.ToLower(filepath.Ext(nameA)), strings.ToLower(filepath.Ext(nameB)))
+	}
+	if c != 0 {
+		return c
+	}
+	return strings.Compare(nameA, nameB)
+}
+
+// naturalCompare は連続した数字を数値として比較する
+// 大文字と小文字は区別せず、それ以外が同じ場合はバイト順で比較する
+func naturalCompare(a string, b string) int {
+	ra := []rune(a)
+	rb := []rune(b)
+
+	i, j := 0, 0
+	for i < len(ra) && j < len(rb) {
+		if unicode.IsDigit(ra[i]) && unicode.IsDigit(rb[j]) {
+			startA, startB := i, j
+			for i < len(ra) && unicode.IsDigit(ra[i]) {
+				i++
+			}
+			for j < len(rb) && unicode.IsDigit(rb[j]) {
+				j++
+			}
+
+			// 先頭の 0 を除いて、桁数、数字の順に比較する
+			numA := strings.TrimLeft(string(ra[startA:i]), "0")
+			numB := strings.TrimLeft(string(rb[startB:j]), "0")
+			if c := cmp.Compare(len(numA), len(numB)); c != 0 {
+				return c
+			}
+			if c := strings.Compare(numA, numB); c != 0 {
+				return c
+			}
+			continue
+		}
+
+		if c := cmp.Compare(unicode.ToLower(ra[i]), unicode.ToLower(rb[j])); c != 0 {
+			return c
+		}
+		i++
+		j++
+	}
+
+	if c := cmp.Compare(len(ra)-i, len(rb)-j); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// sortNodes はノードを現在の並び順に並べ替える。読み込み中の表示などファイル以外のノードは末尾に置く
+func (m *FilesView) sortNodes(nodes []*tview.TreeNode) {
+	slices.SortStableFunc(nodes, func(x *tview.TreeNode, y *tview.TreeNode) int {
+		a, okA := x.GetReference().(*FileNode)
+		b, okB := y.GetReference().(*FileNode)
+		switch {
+		case okA && okB:
+			return m.compareFileNodes(a, b)
+		case okA:
+			return -1
+		case okB:
+			return 1
+		default:
+			return 0
+		}
+	})
+}
+
+// insertSorted は並び順を保つ位置に node を追加する
+func (m *FilesView) insertSorted(parent *tview.TreeNode, node *tview.TreeNode) {
+	fileNode := node.GetReference().(*FileNode)
+	children := parent.GetChildren()
+
+	index := len(children)
+	for i, child := range children {
+		other, ok := child.GetReference().(*FileNode)
+		if ok && m.compareFileNodes(fileNode, other) < 0 {
+			index = i
+			break
+		}
+	}
+
+	parent.SetChildren(slices.Insert(slices.Clone(children), index, node))
+}
+
+// resortTree は node 以下の読み込み済みのノードを並べ替える
+func (m *FilesView) resortTree(node *tview.TreeNode) {
+	children := slices.Clone(node.GetChildren())
+	if len(children) == 0 {
+		return
+	}
+
+	m.sortNodes(children)
+	node.SetChildren(children)
+	for _, child := range children {
+		m.resortTree(child)
+	}
+}
+
+// updateNodeInfo は変更されたファイルのノードの情報を info に置き換え、並び順に影響する場合は位置を直す
+func (m *FilesView) updateNodeInfo(path string, info fs.FileInfo) {
+	parent := m.loadedNode(filepath.Dir(path))
+	if parent == nil {
+		return
+	}
+	node := m.childNodeByPath(parent, path)
+	if node == nil {
+		return
+	}
+	node.GetReference().(*FileNode).info = info
+	m.decorateNode(node)
+
+	if m.SortMode != SortByModTime && m.SortMode != SortBySize {
+		return
+	}
+	m.editChildren(parent, func() {
+		parent.RemoveChild(node)
+		m.insertSorted(parent, node)
+	})
+}
+
+// applySortOrder は並び順の変更をツリーに反映する
+func (m *FilesView) applySortOrder() {
+	m.resortTree(m.TreeView.GetRoot())
+	m.updateSortTitle()
+}
+
+// updateSortTitle は並び順をツリーのタイトルに表示する。デフォルトの並び順では何も表示しない
+func (m *FilesView) updateSortTitle() {
+	if m.SortMode == SortByName && !m.SortDirsFirst && !m.SortReverse {
+		m.TreeView.SetTitle("")
+		return
+	}
+
+	title := m.SortMode.String()
+	if m.SortDirsFirst {
+		title += ", dirs first"
+	}
+	if m.SortReverse {
+		title += ", reversed"
+	}
+	m.TreeView.SetTitle(" sort: " + title + " ")
+}
+
+// cycleSortMode は次の並び順に切り替える
+func (m *FilesView) cycleSortMode() {
+	m.SortMode = (m.SortMode + 1) % SortMode(len(sortModeNames))
+	m.applySortOrder()
+}
+
+// toggleSortReverse は並び順を逆にする
+func (m *FilesView) toggleSortReverse() {
+	m.SortReverse = !m.SortReverse
+	m.applySortOrder()
+}
+
+// toggleDirsFirst はディレクトリを先に表示するかを切り替える
+func (m *FilesView) toggleDirsFirst() {
+	m.SortDirsFirst = !m.SortDirsFirst
+	m.applySortOrder()
+}
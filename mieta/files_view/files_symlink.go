@@ -0,0 +1,121 @@
+package files_view
+
+import (
+	"github.com/rivo/tview"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// fileID はリンクをたどった先が同じディレクトリかを判定するための device と inode の組
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// linkInfo はシンボリックリンクのリンク先の情報
+type linkInfo struct {
+	// リンク先のパス（readlink の結果）
+	target string
+	// リンク先が存在しない
+	broken bool
+	// リンク先がディレクトリ
+	toDir bool
+	// リンク先が祖先のディレクトリなので、たどるとループになる
+	loop bool
+}
+
+// resolveSymlink はシンボリックリンクのリンク先を調べる
+// follow_symlinks が有効ならディレクトリへのリンクをディレクトリとして扱う。ただしループになるリンクはたどらない
+// rootDir はリンクのループを調べる範囲。UI スレッド以外から呼べるように、呼び出し元で RootDir を渡す
+func (m *FilesView) resolveSymlink(fileNode *FileNode, rootDir string) {
+	link := &linkInfo{}
+	fileNode.link = link
+
+	if m.IsRevisionMode() {
+		// リビジョンではリンク先のパスがブロブの内容になっている
+		if content, err := m.readFile(fileNode.Path); err == nil {
+			link.target = string(content)
+		}
+		return
+	}
+
+	target, err := os.Readlink(fileNode.Path)
+	if err != nil {
+		log.Printf("Error reading symlink %s: %v", fileNode.Path, err)
+		return
+	}
+	link.target = target
+
+	// リンク自体がループしている場合も Stat はエラーになる
+	targetInfo, err := os.Stat(fileNode.Path)
+	if err != nil {
+		link.broken = true
+		return
+	}
+	if !targetInfo.IsDir() {
+		return
+	}
+	link.toDir = true
+
+	if !m.FollowSymlinks {
+		return
+	}
+	if isLinkLoop(fileNode.Path, targetInfo, rootDir) {
+		log.Printf("Not following symlink loop: %s -> %s", fileNode.Path, target)
+		link.loop = true
+		return
+	}
+	fileNode.IsDir = true
+}
+
+// isLinkLoop は path のリンク先が path の祖先のディレクトリ（rootDir まで）と同じかを device と inode で判定する
+func isLinkLoop(path string, targetInfo os.FileInfo, rootDir string) bool {
+	targetID, ok := fileIDOf(targetInfo)
+	if !ok {
+		return false
+	}
+
+	for dir := filepath.Dir(path); isUnder(dir, rootDir); dir = filepath.Dir(dir) {
+		if info, err := os.Stat(dir); err == nil {
+			if id, ok := fileIDOf(info); ok && id == targetID {
+				return true
+			}
+		}
+		if dir == rootDir {
+			break
+		}
+	}
+	return false
+}
+
+// linkLabel はノードのラベルに付けるリンク先の表示を返す
+func linkLabel(link *linkInfo) string {
+	label := " [gray]-> " + tview.Escape(link.target) + "[-]"
+	switch {
+	case link.broken:
+		label += " [red](broken)[-]"
+	case link.loop:
+		label += " [red](loop)[-]"
+	}
+	return label
+}
+
+// linkPreviewMessage はリンク先の内容を表示できないシンボリックリンクのプレビューに表示するメッセージを返す
+func (m *FilesView) linkPreviewMessage(fileNode *FileNode) string {
+	link := fileNode.link
+	if link == nil {
+		return ""
+	}
+
+	target := tview.Escape(link.target)
+	switch {
+	case link.broken:
+		return "[red]Broken symbolic link: " + target
+	case link.loop:
+		return "[red]Symbolic link loop: " + target + " is an ancestor of this link"
+	case link.toDir:
+		return "[yellow]Symbolic link to a directory: " + target + "\n\nSet follow_symlinks in the [tree] section of config.toml to browse it."
+	}
+	return ""
+}
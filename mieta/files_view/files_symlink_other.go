@@ -0,0 +1,10 @@
+//go:build !unix
+
+package files_view
+
+import "io/fs"
+
+// fileIDOf は device と inode を取得できない環境では false を返す
+func fileIDOf(info fs.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
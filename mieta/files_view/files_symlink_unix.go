@@ -0,0 +1,17 @@
+//go:build unix
+
+package files_view
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileIDOf はファイルの device と inode を返す
+func fileIDOf(info fs.FileInfo) (fileID, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, false
+	}
+	return fileID{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}, true
+}
@@ -2,6 +2,7 @@ package files_view
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"github.com/alecthomas/chroma/quick"
 	"github.com/fsnotify/fsnotify"
@@ -16,13 +17,15 @@ import (
 	"image/gif"
 	"image/jpeg"
 	"image/png"
+	"io"
+	"io/fs"
 	"log"
 	"os"
 	filepath "path/filepath"
 	"regexp"
 	"strings"
 	"sync"
-	"unicode/utf8"
+	"time"
 )
 
 type FilesView struct {
@@ -33,42 +36,151 @@ type FilesView struct {
 	TreeView         *tview.TreeView
 	PreviewPages     *tview.Pages
 	PreviewImageView *tview.Image
-	PreviewTextView  *tview.TextView
+	// バイナリファイルの 16 進ダンプ
+	HexView         *HexView
+	PreviewTextView *tview.TextView
+	// プレビューと情報パネルを縦に並べる Flex
+	PreviewColumn *tview.Flex
+	InfoTextView  *tview.TextView
 	// 検索モードに入る前に選択されていたアイテム
 	NodeBeforeFinding *tview.TreeNode
-	// 検索キーワード
+	// 検索キーワード。検索モードの間は一致した文字をノードのラベルで強調する
 	FindingKeyword     string
 	CurrentLoadingFile string
 	RootDir            string
 	LeftPane           *tview.Flex
 	FileNameSearchBox  *tview.InputField
+	// フィルタモードの入力欄と、一致した数を表示するステータス行
+	FilterBox          *tview.InputField
+	FilterStatusView   *tview.TextView
 	PreviewTextWrapper *tview.Flex
 	InlineSearchBox    *tview.InputField
 	MaxHighlightId     int
 	CurrentHighlightId int
-
-	// 読み込み中のディレクトリを追跡するためのマップとそのロック
-	loadingDirs      map[string]bool
+	// プレビューに diff を表示するかどうか
+	DiffMode DiffMode
+	// プレビューに blame の情報を表示するかどうか
+	BlameMode bool
+	// ファイルの履歴ページを開く関数
+	OpenHistory func(path string)
+	// 表示している git のリビジョン。空ならワーキングツリーを表示する
+	Revision string
+	// ツリーの並び順
+	SortMode      SortMode
+	SortDirsFirst bool
+	SortReverse   bool
+	// ドットファイルを表示するかどうか
+	ShowHidden bool
+	// ignore されたファイルをグレーで表示する代わりに隠すかどうか
+	HideIgnored bool
+	// ディレクトリへのシンボリックリンクをたどるかどうか
+	FollowSymlinks bool
+	// ノードにサイズや更新日時などの列を表示するかどうか
+	DetailMode bool
+	// プレビューの下に情報パネルを表示するかどうか
+	ShowInfo bool
+	// ブックマークのページを開く関数
+	OpenBookmarks func()
+	// RootDir が変わったときに呼ばれる関数
+	OnRootChanged func(rootDir string)
+
+	// 読み込み中のディレクトリのノードを追跡するためのマップとそのロック
+	// ツリーを作り直したときに同じパスを読み込めるよう、パスではなくノードで管理する
+	loadingDirs      map[*tview.TreeNode]bool
 	loadingDirsMutex sync.Mutex
 	gitTracker       *git.GitTracker
 
-	watcher      *fsnotify.Watcher
-	watchedDirs  map[string]bool
+	watcher     *fsnotify.Watcher
+	watchedDirs map[string]bool
+	// 監視しているディレクトリの device と inode。リンクをたどって同じディレクトリを二度監視しないために使う
+	watchedIDs   map[fileID]string
 	watcherMutex sync.Mutex
+
+	// git status の更新用
+	gitDir         string
+	gitInfoDir     string
+	gitStatusTimer *time.Timer
+	gitStatusMutex sync.Mutex
+	// Close で閉じる。定期的な git status の取得を止める
+	closed chan struct{}
+	// diff 表示中の各行に対応するファイルの行番号
+	diffLineNumbers []int
+	// Revision を解決したコミットハッシュ
+	revisionHash string
+	// マークされたパス
+	marked map[string]bool
+	// セッションの復元で、このファイルを表示したときに戻すスクロール位置
+	restoreScrollPath string
+	restoreScrollRow  int
+	// 以前のルートディレクトリ。新しいものが末尾
+	rootHistory []rootHistoryEntry
+	// ツリーに表示しないファイルのパターン
+	excludePatterns []string
+	// 左ペインの幅
+	treeWidth int
+	// 情報パネルに表示しているパス。古い読み込み結果を捨てるために使う
+	infoPath string
+	// fuzzy finder で検索するファイルの一覧と、開いている finder
+	finderIndex finderIndex
+	finder      *fileFinder
+	// 16 進ダンプで表示しているファイル
+	hexPath string
+	// 拡張子ごとのプレビューの表示方法（整形するかソースのままか）とそのロック
+	previewModes      map[string]string
+	previewModesMutex sync.Mutex
+	// フィルタモードの状態。フィルタモードでなければ nil
+	filter *treeFilter
+	// 検索モードで見つかったノードと、選択しているノードの位置
+	findMatches    []*tview.TreeNode
+	findMatchIndex int
+	// 実行中の再帰的な展開。なければ nil
+	expandJob *expandJob
 }
 
 type FileNode struct {
 	Path  string
 	IsDir bool
+	// ディレクトリの中身を読み込んだかどうか
+	loaded bool
+	// ディレクトリの中身を読み込んでいる途中かどうか
+	loading bool
+	// 読み込みが終わったときに呼ぶ関数
+	onLoaded []func()
+	// サイズや更新日時。取得できなかった場合は nil
+	info fs.FileInfo
+	// シンボリックリンクならリンク先の情報。リンクでなければ nil
+	link *linkInfo
+}
+
+// size はファイルのサイズを返す
+func (n *FileNode) size() int64 {
+	if n.info == nil {
+		return 0
+	}
+	return n.info.Size()
 }
 
-func NewFilesView(rootDir string, config *config.Config, app *tview.Application, pages *tview.Pages) *FilesView {
+// modTime はファイルの更新日時を返す
+func (n *FileNode) modTime() time.Time {
+	if n.info == nil {
+		return time.Time{}
+	}
+	return n.info.ModTime()
+}
+
+func NewFilesView(rootDir string, revision string, config *config.Config, app *tview.Application, pages *tview.Pages) (*FilesView, error) {
+	// リビジョンが指定されている場合は、表示中に変わらないようコミットハッシュに解決しておく
+	var revisionHash string
+	if revision != "" {
+		hash, err := git.ResolveRevision(rootDir, revision)
+		if err != nil {
+			return nil, fmt.Errorf("unknown revision %s: %w", revision, err)
+		}
+		revisionHash = hash
+	}
+
 	// Create tree view
-	root := tview.NewTreeNode(filepath.Base(rootDir))
-	root.SetReference(&FileNode{
-		Path:  rootDir,
-		IsDir: true,
-	})
+	root := newRootNode(rootDir, revision)
 
 	treeView := tview.NewTreeView().
 		SetRoot(root).
@@ -91,9 +203,26 @@ func NewFilesView(rootDir string, config *config.Config, app *tview.Application,
 	previewImageView.SetBorder(true)
 	previewImageView.SetBorderColor(tcell.ColorDarkSlateGray)
 
+	hexView := NewHexView()
+	hexView.SetBorder(true)
+	hexView.SetBorderColor(tcell.ColorDarkSlateGray)
+	hexView.SetBorderPadding(0, 0, 1, 1)
+
 	previewPages := tview.NewPages()
 	previewPages.AddPage("text", previewTextWrapper, true, true)
 	previewPages.AddPage("image", previewImageView, true, false)
+	previewPages.AddPage("hex", hexView, true, false)
+
+	infoTextView := tview.NewTextView().
+		SetDynamicColors(true).
+		SetWrap(false)
+	infoTextView.SetBorder(true)
+	infoTextView.SetBorderColor(tcell.ColorDarkSlateGray)
+	infoTextView.SetBorderPadding(0, 0, 1, 1)
+
+	previewColumn := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(previewPages, 0, 1, false)
 
 	fileNameSearchBox := tview.NewInputField().
 		SetLabel("🔎: ")
@@ -125,9 +254,9 @@ func NewFilesView(rootDir string, config *config.Config, app *tview.Application,
 
 	// Layout
 	flex := tview.NewFlex().
-		AddItem(leftPane, 30, 1, false).
+		AddItem(leftPane, defaultTreeWidth, 1, false).
 		AddItem(tview.NewBox(), 1, 0, false).
-		AddItem(previewPages, 0, 2, false)
+		AddItem(previewColumn, 0, 2, false)
 
 	gitTarcker := git.NewGitTracker()
 	err := gitTarcker.Initialize()
@@ -140,6 +269,11 @@ func NewFilesView(rootDir string, config *config.Config, app *tview.Application,
 		log.Printf("Error creating fsnotify watcher: %v", err)
 	}
 
+	sortMode, err := ParseSortMode(config.Tree.Sort)
+	if err != nil {
+		log.Printf("Invalid tree.sort in config: %v", err)
+	}
+
 	filesView := &FilesView{
 		Application:        app,
 		Config:             config,
@@ -153,15 +287,35 @@ func NewFilesView(rootDir string, config *config.Config, app *tview.Application,
 		InlineSearchBox:    inlineSearchBox,
 		PreviewTextView:    previewTextView,
 		PreviewImageView:   previewImageView,
+		HexView:            hexView,
+		PreviewColumn:      previewColumn,
+		InfoTextView:       infoTextView,
 		RootDir:            rootDir,
-
-		gitTracker:  gitTarcker,
-		loadingDirs: make(map[string]bool),
+		Revision:           revision,
+		SortMode:           sortMode,
+		SortDirsFirst:      config.Tree.DirsFirst,
+		SortReverse:        config.Tree.Reverse,
+		ShowHidden:         config.Tree.ShowHidden,
+		HideIgnored:        config.Tree.HideIgnored,
+		FollowSymlinks:     config.Tree.FollowSymlinks,
+
+		revisionHash:    revisionHash,
+		excludePatterns: validateExcludePatterns(config.Tree.Exclude),
+		treeWidth:       defaultTreeWidth,
+		gitTracker:      gitTarcker,
+		loadingDirs:     make(map[*tview.TreeNode]bool),
+		marked:          make(map[string]bool),
 
 		watcher:     watcher,
 		watchedDirs: make(map[string]bool),
+		watchedIDs:  make(map[fileID]string),
+		closed:      make(chan struct{}),
 	}
 
+	filesView.updateSortTitle()
+	filesView.newFilterBox()
+	filesView.loadPreviewModes()
+
 	inlineSearchBox.SetChangedFunc(func(text string) {
 		filesView.SearchByKeyword(text)
 	})
@@ -171,16 +325,16 @@ func NewFilesView(rootDir string, config *config.Config, app *tview.Application,
 	fileNameSearchBox.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		switch event.Key() {
 		case tcell.KeyEnter:
-			fileNameSearchBox.SetText("")
-			// remove search box from the leftPane
-			leftPane.RemoveItem(fileNameSearchBox)
-			app.SetFocus(treeView)
+			filesView.leaveFindMode(false)
 			return nil
 		case tcell.KeyEscape:
-			treeView.SetCurrentNode(filesView.NodeBeforeFinding)
-			fileNameSearchBox.SetText("")
-			leftPane.RemoveItem(fileNameSearchBox)
-			app.SetFocus(treeView)
+			filesView.leaveFindMode(true)
+			return nil
+		case tcell.KeyDown, tcell.KeyCtrlN, tcell.KeyTab:
+			filesView.findNextMatch()
+			return nil
+		case tcell.KeyUp, tcell.KeyCtrlP, tcell.KeyBacktab:
+			filesView.findPrevMatch()
 			return nil
 		default:
 			return event
@@ -192,6 +346,11 @@ func NewFilesView(rootDir string, config *config.Config, app *tview.Application,
 
 	// キーバインド設定
 	treeView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		// 再帰的な展開の途中なら Esc で中断する
+		if event.Key() == tcell.KeyEscape && filesView.cancelExpansion() {
+			return nil
+		}
+
 		if handler, ok := keycodeKeymap[event.Key()]; ok {
 			handler(filesView)
 			return nil
@@ -207,64 +366,95 @@ func NewFilesView(rootDir string, config *config.Config, app *tview.Application,
 		return event
 	})
 
-	treeView.SetChangedFunc(func(node *tview.TreeNode) {
-		log.Printf("ChangedFunc: %v", node.GetText())
-
-		reference := node.GetReference()
-		if reference == nil {
-			return
-		}
-
-		fileNode := reference.(*FileNode)
-		path := fileNode.Path
-
-		if !fileNode.IsDir {
-			// Load file content
-			filesView.CurrentLoadingFile = path
-			previewTextView.SetTitle(path)
-			previewTextView.SetText("[blue]Loading...")
-			previewPages.SwitchToPage("text")
-			go filesView.loadFileContent(config, path)
-		} else {
-			previewTextView.SetTitle(path)
-			previewTextView.SetText("[yellow]Directory...")
-			previewPages.SwitchToPage("text")
-		}
-	})
+	treeView.SetChangedFunc(filesView.showPreview)
 
 	// Initial loading of the root directory
 	if err := filesView.loadDirectoryContents(root, rootDir); err != nil {
 		log.Printf("Error loading root directory: %v", err)
 	}
 
-	{
-		filesView.watcherMutex.Lock()
-		defer filesView.watcherMutex.Unlock()
-		filesView.startWatching(rootDir)
+	// リビジョンの表示中はファイルが変わらないので監視しない
+	// ディレクトリは読み込んだときに監視し始める
+	if !filesView.IsRevisionMode() {
+		// fsnotifyイベント処理用のgoroutineを起動
+		go filesView.watchEvents()
+
+		go filesView.refreshGitStatus()
+		go filesView.pollGitStatus()
 	}
 
-	// fsnotifyイベント処理用のgoroutineを起動
-	go filesView.watchEvents()
+	return filesView, nil
+}
+
+// showPreview は選択されたノードの内容をプレビューに表示する
+func (m *FilesView) showPreview(node *tview.TreeNode) {
+	log.Printf("ChangedFunc: %v", node.GetText())
+
+	reference := node.GetReference()
+	if reference == nil {
+		return
+	}
 
-	return filesView
+	fileNode := reference.(*FileNode)
+	path := fileNode.Path
+	if path != m.hexPath {
+		m.closeHexDump()
+	}
+
+	if m.ShowInfo {
+		m.startInfo(path)
+	}
+
+	if message := m.linkPreviewMessage(fileNode); message != "" && !fileNode.IsDir {
+		m.CurrentLoadingFile = ""
+		m.PreviewTextView.SetTitle(path)
+		m.PreviewTextView.SetText(message)
+		m.PreviewPages.SwitchToPage("text")
+	} else if !fileNode.IsDir {
+		// Load file content
+		m.CurrentLoadingFile = path
+		m.PreviewTextView.SetTitle(m.previewTitle(path))
+		m.PreviewTextView.SetText("[blue]Loading...")
+		m.PreviewPages.SwitchToPage("text")
+		go m.loadFileContent(m.Config, path)
+	} else {
+		m.CurrentLoadingFile = path
+		m.PreviewTextView.SetTitle(m.previewTitle(path))
+		m.PreviewTextView.SetText("[blue]Loading...")
+		m.PreviewPages.SwitchToPage("text")
+		go m.loadDirectoryPreview(path)
+	}
+}
+
+// reloadPreview は現在選択されているノードのプレビューを読み込み直す
+func (m *FilesView) reloadPreview() {
+	if node := m.TreeView.GetCurrentNode(); node != nil {
+		m.showPreview(node)
+	}
 }
 
 // loadDirectoryContents loads the contents of a directory into a tree node
 func (m *FilesView) loadDirectoryContents(node *tview.TreeNode, path string) error {
 	// ロックを取得してディレクトリの読み込み状態を確認
 	m.loadingDirsMutex.Lock()
-	if m.loadingDirs[path] {
+	if m.loadingDirs[node] {
 		// 既に読み込み中なら何もしない
 		m.loadingDirsMutex.Unlock()
 		return nil
 	}
 
 	// 読み込み中としてマーク
-	m.loadingDirs[path] = true
+	m.loadingDirs[node] = true
 	m.loadingDirsMutex.Unlock()
 
+	if fileNode, ok := node.GetReference().(*FileNode); ok {
+		fileNode.loaded = true
+		fileNode.loading = true
+	}
+
 	// 読み込み中の表示
 	loadingNode := tview.NewTreeNode("[yellow]Loading...")
+	rootDir := m.RootDir
 
 	go func() {
 		m.Application.QueueUpdateDraw(func() {
@@ -277,11 +467,14 @@ func (m *FilesView) loadDirectoryContents(node *tview.TreeNode, path string) err
 		// 処理が終了したらマップから削除するための遅延処理
 		defer func() {
 			m.loadingDirsMutex.Lock()
-			delete(m.loadingDirs, path)
+			delete(m.loadingDirs, node)
 			m.loadingDirsMutex.Unlock()
 		}()
 
-		files, err := os.ReadDir(path)
+		// 読み込んだ中身をファイルの変更に追従させるために、読む前に監視し始める
+		m.watchLoadedDir(path)
+
+		files, err := m.readDir(path)
 		if err != nil {
 			m.Application.QueueUpdateDraw(func() {
 				// ノードがまだ有効かチェック
@@ -293,60 +486,57 @@ func (m *FilesView) loadDirectoryContents(node *tview.TreeNode, path string) err
 						break
 					}
 				}
+				m.finishLoading(node)
 			})
 			return
 		}
 
-		// ファイルをバッチ処理
-		const batchSize = 50
-		var batch []*tview.TreeNode
-
+		// この goroutine ではファイルシステムの読み込みだけを行う
+		// マークや並び順などの UI の状態は UI スレッドでしか変更されないので、ノードの作成と装飾は UI スレッドで行う
+		fileNodes := make([]*FileNode, 0, len(files))
 		for _, file := range files {
-			filePath := filepath.Join(path, file.Name())
-			isDir := file.IsDir()
-
-			var childNode *tview.TreeNode
-			if isDir {
-				childNode = tview.NewTreeNode("📁" + file.Name() + "/")
-			} else {
-				childNode = tview.NewTreeNode("📄" + file.Name())
+			info, err := file.Info()
+			if err != nil {
+				log.Printf("Error getting file info for %s: %v", file.Name(), err)
 			}
+			fileNodes = append(fileNodes, m.newFileNode(filepath.Join(path, file.Name()), file.IsDir(), info, rootDir))
+		}
 
-			childNode.SetReference(&FileNode{
-				Path:  filePath,
-				IsDir: isDir,
-			})
-
-			ignored := m.gitTracker.IsIgnored(filePath)
-			if ignored {
-				childNode.SetColor(tcell.ColorDarkGray)
+		ready := make(chan []*tview.TreeNode, 1)
+		m.Application.QueueUpdate(func() {
+			nodes := make([]*tview.TreeNode, 0, len(fileNodes))
+			for _, fileNode := range fileNodes {
+				if m.isVisible(fileNode.Path, fileNode.IsDir) {
+					nodes = append(nodes, newTreeNode(fileNode))
+				}
 			}
+			m.sortNodes(nodes)
+			ready <- nodes
+		})
+		nodes := <-ready
 
-			batch = append(batch, childNode)
-
-			if len(batch) >= batchSize || file == files[len(files)-1] {
-				nodesToAdd := make([]*tview.TreeNode, len(batch))
-				copy(nodesToAdd, batch)
+		// ファイルをバッチ処理
+		const batchSize = 50
+		for start := 0; start < len(nodes); start += batchSize {
+			nodesToAdd := nodes[start:min(start+batchSize, len(nodes))]
 
-				m.Application.QueueUpdateDraw(func() {
-					// ノードがまだ有効かチェック
-					stillValid := false
-					for _, child := range node.GetChildren() {
-						if child == loadingNode {
-							stillValid = true
-							break
-						}
+			m.Application.QueueUpdateDraw(func() {
+				// ノードがまだ有効かチェック
+				stillValid := false
+				for _, child := range node.GetChildren() {
+					if child == loadingNode {
+						stillValid = true
+						break
 					}
+				}
 
-					if stillValid {
-						for _, n := range nodesToAdd {
-							node.AddChild(n)
-						}
+				if stillValid {
+					for _, n := range nodesToAdd {
+						m.decorateNode(n)
+						node.AddChild(n)
 					}
-				})
-
-				batch = batch[:0]
-			}
+				}
+			})
 		}
 
 		// 読み込み完了後にローディングノードを削除
@@ -357,16 +547,69 @@ func (m *FilesView) loadDirectoryContents(node *tview.TreeNode, path string) err
 					break
 				}
 			}
+			m.finishLoading(node)
 		})
 	}()
 
 	return nil
 }
 
+// finishLoading はディレクトリの読み込みが終わったことを記録し、読み込みを待っていた関数を呼ぶ
+func (m *FilesView) finishLoading(node *tview.TreeNode) {
+	fileNode, ok := node.GetReference().(*FileNode)
+	if !ok {
+		return
+	}
+
+	fileNode.loading = false
+	callbacks := fileNode.onLoaded
+	fileNode.onLoaded = nil
+	for _, callback := range callbacks {
+		callback()
+	}
+}
+
+// afterLoaded はディレクトリの中身を読み込んでから fn を呼ぶ
+// まだ読み込んでいなければ読み込みを始め、読み込み済みならすぐに呼ぶ
+func (m *FilesView) afterLoaded(node *tview.TreeNode, fn func()) {
+	fileNode, ok := node.GetReference().(*FileNode)
+	if !ok || !fileNode.IsDir {
+		return
+	}
+	if fileNode.loaded && !fileNode.loading {
+		fn()
+		return
+	}
+
+	fileNode.onLoaded = append(fileNode.onLoaded, fn)
+	if !fileNode.loaded {
+		if err := m.loadDirectoryContents(node, fileNode.Path); err != nil {
+			log.Printf("Error loading directory: %v", err)
+		}
+	}
+}
+
 // loadFileContent loads and displays file content in the text view with syntax highlighting
 func (m *FilesView) loadFileContent(config *config.Config, path string) {
+	// 画像やバイナリファイルは blame を表示せず、通常のプレビューを表示する
+	blame := m.DiffMode == DiffOff && m.BlameMode && m.canBlame(path)
+
+	// テキストとして読み込む場合は、読み込んだ内容を情報パネルでも使う
+	if m.ShowInfo && (m.DiffMode != DiffOff || blame || isImageFile(path)) {
+		go m.loadFileInfo(path, nil)
+	}
+
+	if m.DiffMode != DiffOff {
+		m.loadDiff(path)
+		return
+	}
+	if blame {
+		m.loadBlame(config, path)
+		return
+	}
+
 	fileExt := filepath.Ext(path)
-	if fileExt == ".jpg" || fileExt == ".jpeg" || fileExt == ".png" || fileExt == ".gif" || fileExt == ".svg" {
+	if isImageFile(path) {
 		log.Printf("Loading image: %s", path)
 		m.loadImage(path, fileExt)
 	} else {
@@ -374,12 +617,18 @@ func (m *FilesView) loadFileContent(config *config.Config, path string) {
 	}
 }
 
+// isImageFile は画像としてプレビューするファイルかを返す
+func isImageFile(path string) bool {
+	fileExt := filepath.Ext(path)
+	return fileExt == ".jpg" || fileExt == ".jpeg" || fileExt == ".png" || fileExt == ".gif" || fileExt == ".svg"
+}
+
 func (m *FilesView) ShowPreviewImage(path string, image *image.Image) {
 	m.Application.QueueUpdateDraw(func() {
 		if m.CurrentLoadingFile == path {
 			log.Printf("Displaying image: %s", path)
 			m.PreviewPages.SwitchToPage("image")
-			m.PreviewImageView.SetTitle(path)
+			m.PreviewImageView.SetTitle(m.previewTitle(path))
 			m.PreviewImageView.SetImage(*image)
 		} else {
 			log.Printf("Ignoring image: %s", path)
@@ -388,12 +637,21 @@ func (m *FilesView) ShowPreviewImage(path string, image *image.Image) {
 }
 
 func (m *FilesView) ShowPreviewText(path string, text string) {
+	m.showPreviewText(path, m.previewTitle(path), text)
+}
+
+// showPreviewText はタイトルを指定してプレビューにテキストを表示する
+func (m *FilesView) showPreviewText(path string, title string, text string) {
 	m.Application.QueueUpdateDraw(func() {
 		if m.CurrentLoadingFile == path {
 			log.Printf("Displaying text: %s", path)
-			m.PreviewTextView.SetTitle(path)
+			m.PreviewTextView.SetTitle(title)
 			m.PreviewTextView.SetText(text)
 			m.PreviewPages.SwitchToPage("text")
+			if m.restoreScrollPath == path {
+				m.PreviewTextView.ScrollTo(m.restoreScrollRow, 0)
+				m.restoreScrollPath = ""
+			}
 		} else {
 			log.Printf("Ignoring text: %s", path)
 		}
@@ -401,12 +659,12 @@ func (m *FilesView) ShowPreviewText(path string, text string) {
 }
 
 func (m *FilesView) loadImage(path string, fileExt string) {
-	file, err := os.Open(path)
+	file, err := m.openFile(path)
 	if err != nil {
 		log.Printf("Failed to open image file: %v", err)
 		return
 	}
-	defer func(file *os.File) {
+	defer func(file io.ReadCloser) {
 		err := file.Close()
 		if err != nil {
 			log.Printf("Failed to close image file: %v", err)
@@ -452,83 +710,103 @@ func (m *FilesView) loadTextFile(config *config.Config, path string) {
 	m.PreviewPages.SwitchToPage("text")
 
 	log.Printf("Loading %s", path)
-	content, err := os.ReadFile(path)
+	// バイナリファイルは全体を読み込まずに、表示する範囲だけを読み込む
+	// 文字コードが指定されているファイルはテキストとして扱う
+	if !m.IsRevisionMode() && configuredCharset(m.Config, m.slashRelativePath(path)) == nil {
+		if binary, err := isBinaryFile(path); err == nil && binary {
+			if m.ShowInfo {
+				go m.loadFileInfo(path, nil)
+			}
+			m.loadHexDump(path)
+			return
+		}
+	}
+
+	content, err := m.readFile(path)
+	if m.ShowInfo {
+		go m.loadFileInfo(path, content)
+	}
 	if err != nil {
 		m.ShowPreviewText(path, fmt.Sprintf("[red]Error loading file: %v", err))
 		return
 	}
 	log.Printf("Finished reading %s(%d bytes)", path, len(content))
 
-	if !utf8.Valid(content) {
-		m.ShowPreviewText(path, "[red]Binary")
+	c := m.charsetOf(path, content)
+	if c == nil {
+		m.showHexDump(path, bytes.NewReader(content), int64(len(content)), nil)
+		return
+	}
+	text, err := c.Decode(content)
+	if err != nil {
+		m.ShowPreviewText(path, fmt.Sprintf("[red]Failed to decode as %s: %v", c, err))
 		return
 	}
 
+	title := fmt.Sprintf("%s (%s)", m.previewTitle(path), c)
+	m.showPreviewText(path, title, m.formatText(path, string(text)))
+}
+
+// HighlightContent はファイルの拡張子に応じてシンタックスハイライトしたテキストを返す
+// ハイライトできない場合や大きすぎる場合はそのまま返す
+func HighlightContent(config *config.Config, path string, content string) string {
 	highlightLimit := config.HighlightLimit
 	if len(content) > highlightLimit {
 		log.Printf("File is too large to highlight: %s(%d bytes > %d bytes)", path,
 			len(content), highlightLimit)
-		m.ShowPreviewText(path, string(content))
-		return
+		return content
 	}
 
 	// Detect syntax highlighting based on file extension
 	fileExt := filepath.Ext(path)
 
 	var highlighted bytes.Buffer
-	if err := quick.Highlight(&highlighted, string(content), fileExt, "terminal", config.ChromaStyle); err == nil {
-		m.ShowPreviewText(path, tview.TranslateANSI(highlighted.String()))
-	} else {
-		m.ShowPreviewText(path, string(content))
+	if err := quick.Highlight(&highlighted, content, fileExt, "terminal", config.ChromaStyle); err == nil {
+		return tview.TranslateANSI(highlighted.String())
 	}
+	return content
 }
 
+// findByKeyword はファイル名に keyword の文字が順番に含まれるノードを探して、最初に見つかったノードを選択する
+// 見つかったノードは findNextMatch/findPrevMatch で順に選択できる
 func (m *FilesView) findByKeyword(keyword string) {
-	keyword = strings.ToLower(keyword)
 	log.Printf("Finding: %s", keyword)
-	if keyword == "" {
-		return
-	}
-
-	// Function to check if a string contains all characters of the keyword in order
-	matches := func(text, keyword string) bool {
-		text = strings.ToLower(text)
-		j := 0
-		for i := 0; i < len(text) && j < len(keyword); i++ {
-			if text[i] == keyword[j] {
-				j++
+	m.FindingKeyword = keyword
+	m.findMatches = nil
+	m.findMatchIndex = 0
+
+	if keyword != "" {
+		// 読み込み済みのノードを深さ優先で探す
+		var searchNode func(node *tview.TreeNode)
+		searchNode = func(node *tview.TreeNode) {
+			for _, child := range node.GetChildren() {
+				fileNode, ok := child.GetReference().(*FileNode)
+				if !ok {
+					continue
+				}
+				// ラベルには git status の記号などが付くので、ファイル名で比較する
+				if findPositions(keyword, filepath.Base(fileNode.Path)) != nil {
+					m.findMatches = append(m.findMatches, child)
+				}
+				searchNode(child)
 			}
 		}
-		return j == len(keyword)
+		searchNode(m.TreeView.GetRoot())
 	}
 
-	// Function to search nodes recursively
-	var searchNode func(node *tview.TreeNode) *tview.TreeNode
-	searchNode = func(node *tview.TreeNode) *tview.TreeNode {
-		// Check current node
-		text := node.GetText()
-		if matches(text, keyword) {
-			return node
-		}
-
-		// Check children
-		for _, child := range node.GetChildren() {
-			if found := searchNode(child); found != nil {
-				return found
-			}
-		}
-
-		return nil
-	}
+	// 一致した文字を強調するためにラベルを作り直す
+	m.refreshNodeStyles(m.TreeView.GetRoot())
 
-	// Start search from root
-	if found := searchNode(m.TreeView.GetRoot()); found != nil {
-		m.TreeView.SetCurrentNode(found)
-	} else {
+	if len(m.findMatches) > 0 {
+		m.selectNode(m.findMatches[0])
+	} else if keyword != "" {
 		// If no match found, revert to original node
 		log.Printf("Not Found: %s", keyword)
-		m.TreeView.SetCurrentNode(m.NodeBeforeFinding)
+		if m.NodeBeforeFinding != nil {
+			m.TreeView.SetCurrentNode(m.NodeBeforeFinding)
+		}
 	}
+	m.updateFindLabel()
 }
 
 func (m *FilesView) expand() {
@@ -579,8 +857,17 @@ func (m *FilesView) Edit() {
 		return
 	}
 
+	if m.IsRevisionMode() {
+		log.Printf("Editing is disabled while browsing revision %s", m.Revision)
+		return
+	}
+
 	// Open in external editor
 	lineNumber := mieta.GetCurrentLineNumber(m.PreviewTextView)
+	if m.DiffMode != DiffOff {
+		// diff 表示中は表示している hunk の行に対応するファイルの行を開く
+		lineNumber = m.diffLineNumber(lineNumber)
+	}
 	mieta.OpenInEditor(m.Application, m.Config, fileNode.Path, lineNumber)
 }
 
@@ -677,44 +964,92 @@ func (m *FilesView) findPrev() {
 	m.PreviewTextView.ScrollToHighlight()
 }
 
-func (m *FilesView) startWatching(path string) {
+// watchLoadedDir は中身をツリーに読み込んだディレクトリを監視する
+// 監視するのは読み込んだディレクトリだけで、その下のディレクトリは展開して読み込んだときに監視する
+func (m *FilesView) watchLoadedDir(path string) {
+	if m.watcher == nil || m.IsRevisionMode() {
+		return
+	}
+
+	m.watcherMutex.Lock()
+	defer m.watcherMutex.Unlock()
+	m.watchDir(path)
+}
+
+// watchDir はディレクトリを監視する。watcherMutex を取得した状態で呼ぶこと
+func (m *FilesView) watchDir(path string) {
 	// 既に監視中なら何もしない
 	if m.watchedDirs[path] {
 		return
 	}
 
+	// .git の中は展開や絞り込みで読み込んでも監視しない。index などの変更は watchGitDir で監視する
 	_, file := filepath.Split(path)
-	if file == ".git" {
+	if file == ".git" || (m.gitDir != "" && isUnder(path, m.gitDir)) {
 		log.Printf("Ignore .git directory: %s", path)
 		return
 	}
+	if m.isExcluded(path) {
+		log.Printf("Ignore excluded directory: %s", path)
+		return
+	}
+
+	// リンクをたどった先が監視中のディレクトリなら、同じディレクトリへの別名なので監視しない
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return
+	}
+	id, hasID := fileIDOf(info)
+	if watchedPath, ok := m.watchedIDs[id]; hasID && ok {
+		log.Printf("Ignore directory already watched as %s: %s", watchedPath, path)
+		return
+	}
 
 	// ディレクトリを監視対象に追加
-	err := m.watcher.Add(path)
+	err = m.watcher.Add(path)
 	if err != nil {
 		log.Printf("Error watching directory %s: %v", path, err)
 		return
 	}
 
 	m.watchedDirs[path] = true
+	if hasID {
+		m.watchedIDs[id] = path
+	}
 	log.Printf("Started watching directory: %s", path)
+}
 
-	// サブディレクトリも再帰的に監視
-	files, err := os.ReadDir(path)
-	if err != nil {
-		log.Printf("Error reading directory %s: %v", path, err)
+// unwatchDir はディレクトリの監視をやめる。watcherMutex を取得した状態で呼ぶこと
+func (m *FilesView) unwatchDir(path string) {
+	if !m.watchedDirs[path] {
 		return
 	}
 
-	log.Printf("Found %d files", len(files))
-	for _, file := range files {
-		if file.IsDir() {
-			subPath := filepath.Join(path, file.Name())
-			m.startWatching(subPath)
+	// 削除されたディレクトリは監視が外れている
+	if err := m.watcher.Remove(path); err != nil && !errors.Is(err, fsnotify.ErrNonExistentWatch) {
+		log.Printf("Error unwatching directory %s: %v", path, err)
+	}
+	delete(m.watchedDirs, path)
+	for id, watchedPath := range m.watchedIDs {
+		if watchedPath == path {
+			delete(m.watchedIDs, id)
 		}
 	}
+}
 
-	log.Printf("Started watching directory: %s", path)
+// unwatchTree はツリーに読み込んだディレクトリの監視をすべてやめる。.git の中の監視は残す
+func (m *FilesView) unwatchTree() {
+	if m.watcher == nil {
+		return
+	}
+
+	m.watcherMutex.Lock()
+	defer m.watcherMutex.Unlock()
+	for path := range m.watchedDirs {
+		if path != m.gitDir && path != m.gitInfoDir {
+			m.unwatchDir(path)
+		}
+	}
 }
 
 func (m *FilesView) watchEvents() {
@@ -745,34 +1080,77 @@ func (m *FilesView) handleFsEvent(event fsnotify.Event) {
 
 	log.Printf("FS event: %v", event)
 
+	m.watcherMutex.Lock()
+	gitDir := m.gitDir
+	gitInfoDir := m.gitInfoDir
+	m.watcherMutex.Unlock()
+	// .git/info/exclude の変更は ignore ルールの更新だけ行う
+	if gitInfoDir != "" && filepath.Dir(event.Name) == gitInfoDir {
+		if m.gitTracker.InvalidateIgnoreRules(event.Name) {
+			m.ignoreRulesChanged()
+			m.scheduleGitStatusRefresh()
+		}
+		return
+	}
+	// .git ディレクトリ内のイベントは git status の更新だけ行う
+	// 見るのは .git 直下の index と HEAD と config だけで、objects や refs の中の変更は無視する
+	if gitDir != "" && isUnder(event.Name, gitDir) {
+		if filepath.Dir(event.Name) != gitDir {
+			return
+		}
+		base := filepath.Base(event.Name)
+		if base == "index" || base == "HEAD" {
+			m.scheduleGitStatusRefresh()
+		}
+		// .git/config で core.excludesFile が変わることがあるので ignore ルールを読み直す
+		if m.gitTracker.InvalidateIgnoreRules(event.Name) {
+			m.ignoreRulesChanged()
+			m.scheduleGitStatusRefresh()
+		}
+		return
+	}
+	m.scheduleGitStatusRefresh()
+
+	// .gitignore が変更された場合は読み込み済みのノードの色を更新する
+	if m.gitTracker.InvalidateIgnoreRules(event.Name) {
+		m.ignoreRulesChanged()
+	}
+
 	// ディレクトリの作成イベント
 	if event.Op&fsnotify.Create == fsnotify.Create {
-		fileInfo, err := os.Stat(event.Name)
+		fileInfo, err := os.Lstat(event.Name)
 		if err != nil {
 			log.Printf("Error getting file info for %s: %v", event.Name, err)
 			return
 		}
 
-		// 新しいディレクトリが作成された場合は監視対象に追加
-		if fileInfo.IsDir() {
-			m.startWatching(event.Name)
-		}
+		// 新しいディレクトリは展開して読み込むまで監視しない
+		m.invalidateFinderIndex()
 
 		// UIツリーに新しいノードを追加
 		m.Application.QueueUpdateDraw(func() {
-			m.addNodeForPath(event.Name, fileInfo.IsDir())
+			if m.isVisible(event.Name, fileInfo.IsDir()) {
+				m.addNodeForPath(event.Name, fileInfo)
+			}
 		})
 	}
 
-	// ファイル/ディレクトリの削除イベント
-	if event.Op&fsnotify.Remove == fsnotify.Remove {
+	// ファイル/ディレクトリの削除イベント（リネーム元も削除として扱う）
+	if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		m.invalidateFinderIndex()
+
 		m.Application.QueueUpdateDraw(func() {
 			m.removeNodeForPath(event.Name)
 		})
 
-		// 監視リストから削除
+		// 監視リストから、そのディレクトリと下のディレクトリを削除する
+		// リネームされたディレクトリは監視が残っているので外す
 		m.watcherMutex.Lock()
-		delete(m.watchedDirs, event.Name)
+		for path := range m.watchedDirs {
+			if isUnder(path, event.Name) {
+				m.unwatchDir(path)
+			}
+		}
 		m.watcherMutex.Unlock()
 	}
 
@@ -782,82 +1160,243 @@ func (m *FilesView) handleFsEvent(event fsnotify.Event) {
 		if m.CurrentLoadingFile == event.Name {
 			go m.loadFileContent(m.Config, event.Name)
 		}
+
+		// サイズや更新日時が変わるので、並び順を直す
+		// 作成されたときと同じく、リンクはたどらずに情報を読む
+		fileInfo, err := os.Lstat(event.Name)
+		if err != nil {
+			log.Printf("Error getting file info for %s: %v", event.Name, err)
+			return
+		}
+		m.Application.QueueUpdateDraw(func() {
+			m.updateNodeInfo(event.Name, fileInfo)
+		})
 	}
 }
 
 // パスに対応するノードをツリーに追加する関数
-func (m *FilesView) addNodeForPath(path string, isDir bool) {
+// 追加したノード（既にある場合はそのノード）を返す
+func (m *FilesView) addNodeForPath(path string, info fs.FileInfo) *tview.TreeNode {
 	// パスの親ディレクトリを特定
 	parentPath := filepath.Dir(path)
-	fileName := filepath.Base(path)
 
 	// 親ノードを探す
-	parentNode := m.findNodeByPath(m.TreeView.GetRoot(), parentPath)
+	parentNode := m.loadedNode(parentPath)
 	if parentNode == nil {
 		log.Printf("Cannot find parent node for %s", path)
-		return
+		return nil
 	}
 
-	// 既に同名のノードがあるか確認
-	for _, child := range parentNode.GetChildren() {
-		ref := child.GetReference()
-		if ref == nil {
-			continue
-		}
+	// まだ中身を読み込んでいないディレクトリは、展開したときに読み込まれる
+	if !parentNode.GetReference().(*FileNode).loaded {
+		return nil
+	}
 
-		fileNode := ref.(*FileNode)
-		if fileNode.Path == path {
-			// 既に存在する場合は何もしない
-			return
-		}
+	// 既に同名のノードがある場合は何もしない
+	if child := m.childNodeByPath(parentNode, path); child != nil {
+		return child
 	}
 
-	// 新しいノードを作成
-	var newNode *tview.TreeNode
-	if isDir {
-		newNode = tview.NewTreeNode("📁" + fileName + "/")
-	} else {
-		newNode = tview.NewTreeNode("📄" + fileName)
+	// 新しいノードを作成して、並び順を保つ位置に追加
+	newNode := m.newFileTreeNode(path, info.IsDir(), info)
+	m.editChildren(parentNode, func() {
+		m.insertSorted(parentNode, newNode)
+	})
+	// 絞り込み中に増えたディレクトリは、中身も一致するか調べる
+	if info.IsDir() {
+		m.filterNewDir(newNode)
 	}
+	return newNode
+}
 
-	newNode.SetReference(&FileNode{
+// newRootNode はツリーのルートになるノードを作成する
+func newRootNode(rootDir string, revision string) *tview.TreeNode {
+	rootLabel := filepath.Base(rootDir)
+	if revision != "" {
+		rootLabel += " @ " + tview.Escape(revision)
+	}
+	root := tview.NewTreeNode(rootLabel)
+	root.SetReference(&FileNode{
+		Path:  rootDir,
+		IsDir: true,
+	})
+	return root
+}
+
+// newFileTreeNode はファイル/ディレクトリを表すノードを作成する
+func (m *FilesView) newFileTreeNode(path string, isDir bool, info fs.FileInfo) *tview.TreeNode {
+	node := newTreeNode(m.newFileNode(path, isDir, info, m.RootDir))
+	m.decorateNode(node)
+	return node
+}
+
+// newFileNode はファイル/ディレクトリの情報を作成する。シンボリックリンクならリンク先も調べる
+// UI の状態は参照しないので、UI スレッド以外からも呼べる
+func (m *FilesView) newFileNode(path string, isDir bool, info fs.FileInfo, rootDir string) *FileNode {
+	fileNode := &FileNode{
 		Path:  path,
 		IsDir: isDir,
-	})
+		info:  info,
+	}
+	if info != nil && info.Mode()&fs.ModeSymlink != 0 {
+		m.resolveSymlink(fileNode, rootDir)
+	}
+	return fileNode
+}
+
+// newTreeNode は fileNode を表すノードを作成する。ラベルと色は decorateNode で設定する
+func newTreeNode(fileNode *FileNode) *tview.TreeNode {
+	// ディレクトリは読み込んでも展開するまで中身を表示しない
+	return tview.NewTreeNode("").
+		SetExpanded(false).
+		SetReference(fileNode)
+}
+
+// decorateNode はノードのラベルと色を ignore ルールや git status に合わせて設定する
+func (m *FilesView) decorateNode(node *tview.TreeNode) {
+	fileNode := node.GetReference().(*FileNode)
+
+	name := tview.Escape(filepath.Base(fileNode.Path))
+	if positions := findPositions(m.FindingKeyword, filepath.Base(fileNode.Path)); positions != nil {
+		name = highlightPositions(filepath.Base(fileNode.Path), positions)
+	}
+	icon, suffix := "📄", ""
+	if fileNode.IsDir {
+		icon, suffix = "📁", "/"
+	}
+	if fileNode.link != nil {
+		icon = "🔗"
+	}
+	label := icon + name + suffix
+	if fileNode.link != nil {
+		label += linkLabel(fileNode.link)
+	}
 
-	// Gitで無視されているかチェック
-	ignored := m.gitTracker.IsIgnored(path)
-	if ignored {
-		newNode.SetColor(tcell.ColorDarkGray)
+	color := tcell.ColorWhite
+	if fileNode.link != nil && (fileNode.link.broken || fileNode.link.loop) {
+		color = tcell.ColorRed
+	} else if m.IsRevisionMode() {
+		// リビジョンに含まれるファイルはすべて追跡されているので ignore や status は見ない
+	} else if m.gitTracker.IsIgnored(fileNode.Path, fileNode.IsDir) {
+		color = tcell.ColorDarkGray
+	} else if status := m.gitTracker.Status(fileNode.Path); status != 0 {
+		color = gitStatusColor(status)
+		label += " " + gitStatusBadge(status)
 	}
 
-	// 親ノードに追加
-	parentNode.AddChild(newNode)
+	if m.isMarked(fileNode.Path) {
+		label = markPrefix + label
+	}
+	if m.DetailMode {
+		label = m.appendDetailColumns(label, fileNode)
+	}
+
+	node.SetText(label)
+	node.SetColor(color)
 }
 
 func (m *FilesView) removeNodeForPath(path string) {
+	// 削除されたパスのマークは外す
+	m.unmarkPath(path)
+
 	// 親パスを特定
 	parentPath := filepath.Dir(path)
 
 	// 親ノードを探す
-	parentNode := m.findNodeByPath(m.TreeView.GetRoot(), parentPath)
+	parentNode := m.loadedNode(parentPath)
 	if parentNode == nil {
 		return
 	}
 
 	// 子ノードを探して削除
-	for _, child := range parentNode.GetChildren() {
-		ref := child.GetReference()
-		if ref == nil {
+	child := m.childNodeByPath(parentNode, path)
+	if child == nil {
+		return
+	}
+	m.editChildren(parentNode, func() {
+		parentNode.RemoveChild(child)
+	})
+}
+
+// refreshNodeStyles は node 以下の読み込み済みノードのラベルと色を更新する
+func (m *FilesView) refreshNodeStyles(node *tview.TreeNode) {
+	for _, child := range node.GetChildren() {
+		if child.GetReference() == nil {
 			continue
 		}
 
-		fileNode := ref.(*FileNode)
-		if fileNode.Path == path {
-			parentNode.RemoveChild(child)
-			return
+		m.decorateNode(child)
+		m.refreshNodeStyles(child)
+	}
+}
+
+// selectedFileNode は選択中のノードとその FileNode を返す。選択されていなければ nil を返す
+func (m *FilesView) selectedFileNode() (*tview.TreeNode, *FileNode) {
+	node := m.TreeView.GetCurrentNode()
+	if node == nil {
+		return nil, nil
+	}
+	fileNode, ok := node.GetReference().(*FileNode)
+	if !ok {
+		return nil, nil
+	}
+	return node, fileNode
+}
+
+// selectNode は node の親ディレクトリをすべて展開してから node を選択し、プレビューを更新する
+func (m *FilesView) selectNode(node *tview.TreeNode) {
+	for _, ancestor := range m.TreeView.GetPath(node) {
+		if ancestor != node {
+			ancestor.Expand()
 		}
 	}
+	m.TreeView.SetCurrentNode(node)
+	m.showPreview(node)
+}
+
+// loadedNode は RootDir から path までのディレクトリをたどって path のノードを探す
+// ツリー全体を探す findNodeByPath と違い、途中のディレクトリの子だけを調べる。読み込まれていなければ nil を返す
+// フィルタで隠れているノードも探す
+func (m *FilesView) loadedNode(path string) *tview.TreeNode {
+	nodes := m.loadedNodePath(path)
+	if len(nodes) == 0 {
+		return nil
+	}
+	return nodes[len(nodes)-1]
+}
+
+// loadedNodePath はルートから path のノードまでのノードを返す。読み込まれていなければ nil を返す
+func (m *FilesView) loadedNodePath(path string) []*tview.TreeNode {
+	node := m.TreeView.GetRoot()
+	if node == nil || !isUnder(path, m.RootDir) {
+		return nil
+	}
+	nodes := []*tview.TreeNode{node}
+	relPath, err := filepath.Rel(m.RootDir, path)
+	if err != nil || relPath == "." {
+		return nodes
+	}
+
+	current := m.RootDir
+	for _, name := range strings.Split(relPath, string(filepath.Separator)) {
+		current = filepath.Join(current, name)
+		node = m.childNodeByPath(node, current)
+		if node == nil {
+			return nil
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// childNodeByPath は parent の子から path のノードを探す。フィルタで隠れている子も探す
+func (m *FilesView) childNodeByPath(parent *tview.TreeNode, path string) *tview.TreeNode {
+	for _, child := range m.allChildren(parent) {
+		if fileNode, ok := child.GetReference().(*FileNode); ok && fileNode.Path == path {
+			return child
+		}
+	}
+	return nil
 }
 
 // パスからノードを探す関数
@@ -887,4 +1426,5 @@ func (m *FilesView) Close() {
 	if m.watcher != nil {
 		m.watcher.Close()
 	}
+	close(m.closed)
 }
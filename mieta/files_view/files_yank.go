@@ -0,0 +1,105 @@
+package files_view
+
+import (
+	"fmt"
+	"github.com/rivo/tview"
+	"github.com/tokuhirom/mieta/mieta"
+	"github.com/tokuhirom/mieta/mieta/clipboard"
+	"log"
+	"strings"
+)
+
+// yank はテキストをクリップボードにコピーする
+func (m *FilesView) yank(text string) {
+	if err := clipboard.Copy(text); err != nil {
+		m.showMessageDialog(fmt.Sprintf("Failed to copy to clipboard: %s", tview.Escape(err.Error())))
+		return
+	}
+	log.Printf("Copied to clipboard: %q", truncate(text, 80))
+}
+
+// yankAbsolutePath は選択中のノードの絶対パスをコピーする
+func (m *FilesView) yankAbsolutePath() {
+	if _, fileNode := m.selectedFileNode(); fileNode != nil {
+		m.yank(fileNode.Path)
+	}
+}
+
+// yankRelativePath は選択中のノードの RootDir からの相対パスをコピーする
+func (m *FilesView) yankRelativePath() {
+	if _, fileNode := m.selectedFileNode(); fileNode != nil {
+		m.yank(m.relativePath(fileNode.Path))
+	}
+}
+
+// yankPathWithLine はプレビューで表示している行を "path:line" の形式でコピーする
+func (m *FilesView) yankPathWithLine() {
+	_, fileNode := m.selectedFileNode()
+	if fileNode == nil {
+		return
+	}
+	// 16 進ダンプには行がないので、パスだけをコピーする
+	if fileNode.IsDir || m.showingHexDump() {
+		m.yank(m.relativePath(fileNode.Path))
+		return
+	}
+
+	lineNumber := mieta.GetCurrentLineNumber(m.PreviewTextView)
+	if m.DiffMode != DiffOff {
+		lineNumber = m.diffLineNumber(lineNumber)
+	}
+	m.yank(fmt.Sprintf("%s:%d", m.relativePath(fileNode.Path), lineNumber))
+}
+
+// yankPreviewLines はプレビューに表示されている範囲の行をコピーする
+// ソース表示と blame 表示ではファイルの内容を、diff 表示では diff をそのままコピーする
+func (m *FilesView) yankPreviewLines() {
+	_, fileNode := m.selectedFileNode()
+	if fileNode == nil || fileNode.IsDir {
+		return
+	}
+	// 16 進ダンプの表示中はテキストのプレビューが古いままで、ファイルも大きいことがあるので読まない
+	if m.showingHexDump() {
+		m.showMessageDialog("Cannot copy lines of a hex dump.")
+		return
+	}
+	// 整形した Markdown の行はファイルの行と対応しない
+	if m.showingRenderedMarkdown(fileNode.Path) {
+		m.showMessageDialog("Cannot copy lines of rendered Markdown. Press M to show the source.")
+		return
+	}
+
+	start, end := mieta.GetVisibleLineRange(m.PreviewTextView)
+	if start == 0 {
+		return
+	}
+
+	var lines []string
+	if m.DiffMode != DiffOff {
+		lines = strings.Split(m.PreviewTextView.GetText(true), "\n")
+	} else {
+		// blame のガターや色のタグを含まないように、ファイルから読み直す
+		content, err := m.readFile(fileNode.Path)
+		if err != nil {
+			m.showOperationError("read", fileNode.Path, err)
+			return
+		}
+		// プレビューと同じように文字コードを変換する
+		c := m.charsetOf(fileNode.Path, content)
+		if c == nil {
+			return
+		}
+		text, err := c.Decode(content)
+		if err != nil {
+			m.showMessageDialog(fmt.Sprintf("Failed to decode as %s: %s", c, tview.Escape(err.Error())))
+			return
+		}
+		lines = strings.Split(string(text), "\n")
+	}
+
+	if start > len(lines) {
+		return
+	}
+	end = min(end, len(lines))
+	m.yank(strings.Join(lines[start-1:end], "\n"))
+}
@@ -0,0 +1,172 @@
+package fuzzy
+
+import (
+	"unicode"
+)
+
+// スコアの重み
+const (
+	// 1 文字一致するごとの点数
+	scoreMatch = 16
+	// 一致した文字の間が空いたときの減点（最初の 1 文字とそれ以降の 1 文字ごと）
+	penaltyGapStart  = 3
+	penaltyGapExtend = 1
+	// 前の文字と続けて一致したときの加点
+	bonusConsecutive = 8
+	// パスの区切り（"/"）の直後やパスの先頭で一致したときの加点
+	bonusSegment = 10
+	// 単語の区切り（"_", "-", "." など）の直後で一致したときの加点
+	bonusBoundary = 8
+	// camelCase の大文字で一致したときの加点
+	bonusCamel = 7
+	// ファイル名の部分で一致したときの加点
+	bonusBaseName = 2
+)
+
+// noScore は一致しない位置を表す
+const noScore = -1 << 30
+
+// Match は pattern の文字が text に順番に含まれているかを調べ、一致の良さを点数にして返す
+// 単語やパスの区切りで一致するほど、連続して一致するほど点数が高くなる
+// pattern に大文字が含まれる場合だけ大文字と小文字を区別する
+// positions は一致した text の文字（rune）の位置
+func Match(pattern string, text string) (score int, positions []int, ok bool) {
+	p := []rune(pattern)
+	t := []rune(text)
+	if len(p) == 0 {
+		return 0, nil, true
+	}
+	if len(p) > len(t) {
+		return 0, nil, false
+	}
+
+	caseSensitive := false
+	for _, r := range p {
+		if unicode.IsUpper(r) {
+			caseSensitive = true
+			break
+		}
+	}
+	equal := func(a rune, b rune) bool {
+		if caseSensitive {
+			return a == b
+		}
+		return unicode.ToLower(a) == unicode.ToLower(b)
+	}
+
+	// 部分列として含まれていなければ点数を計算するまでもない
+	if !isSubsequence(p, t, equal) {
+		return 0, nil, false
+	}
+
+	baseNameStart := 0
+	for i, r := range t {
+		if r == '/' {
+			baseNameStart = i + 1
+		}
+	}
+
+	bonuses := make([]int, len(t))
+	for j := range t {
+		bonuses[j] = positionBonus(t, j)
+		if j >= baseNameStart {
+			bonuses[j] += bonusBaseName
+		}
+	}
+
+	// scores[i][j] は pattern[i] を text[j] に一致させたときの pattern[:i+1] の最高点
+	// from[i][j] はそのときの pattern[i-1] の位置
+	n := len(t)
+	scores := make([][]int, len(p))
+	from := make([][]int, len(p))
+	for i := range p {
+		scores[i] = make([]int, n)
+		from[i] = make([]int, n)
+		for j := range scores[i] {
+			scores[i][j] = noScore
+		}
+	}
+
+	for j := range t {
+		if equal(p[0], t[j]) {
+			scores[0][j] = scoreMatch + bonuses[j]
+		}
+	}
+
+	for i := 1; i < len(p); i++ {
+		// 1 文字以上空けて pattern[i-1] に一致した位置の中で、減点を考慮した最高点
+		bestGap, bestGapIndex := noScore, -1
+		for j := 1; j < n; j++ {
+			if j >= 2 {
+				bestGap -= penaltyGapExtend
+				if candidate := scores[i-1][j-2] - penaltyGapStart; candidate > bestGap {
+					bestGap, bestGapIndex = candidate, j-2
+				}
+			}
+			if !equal(p[i], t[j]) {
+				continue
+			}
+
+			best, bestIndex := noScore, -1
+			if prev := scores[i-1][j-1]; prev > noScore {
+				best, bestIndex = prev+bonusConsecutive, j-1
+			}
+			if bestGap > noScore/2 && bestGap > best {
+				best, bestIndex = bestGap, bestGapIndex
+			}
+			if bestIndex < 0 {
+				continue
+			}
+			scores[i][j] = best + scoreMatch + bonuses[j]
+			from[i][j] = bestIndex
+		}
+	}
+
+	last := len(p) - 1
+	bestIndex := -1
+	for j := range t {
+		if scores[last][j] > noScore && (bestIndex < 0 || scores[last][j] > scores[last][bestIndex]) {
+			bestIndex = j
+		}
+	}
+	if bestIndex < 0 {
+		return 0, nil, false
+	}
+
+	positions = make([]int, len(p))
+	for i, j := last, bestIndex; i >= 0; i-- {
+		positions[i] = j
+		j = from[i][j]
+	}
+	return scores[last][bestIndex], positions, true
+}
+
+// positionBonus は text[j] で一致したときの区切りによる加点を返す
+func positionBonus(t []rune, j int) int {
+	if j == 0 {
+		return bonusSegment
+	}
+
+	prev := t[j-1]
+	switch {
+	case prev == '/':
+		return bonusSegment
+	case prev == '_' || prev == '-' || prev == '.' || prev == ' ':
+		return bonusBoundary
+	case unicode.IsLower(prev) && unicode.IsUpper(t[j]):
+		return bonusCamel
+	case !unicode.IsDigit(prev) && unicode.IsDigit(t[j]):
+		return bonusBoundary / 2
+	}
+	return 0
+}
+
+func isSubsequence(p []rune, t []rune, equal func(a rune, b rune) bool) bool {
+	i := 0
+	for _, r := range t {
+		if i < len(p) && equal(p[i], r) {
+			i++
+		}
+	}
+	return i == len(p)
+}
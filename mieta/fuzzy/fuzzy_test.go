@@ -0,0 +1,83 @@
+package fuzzy
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		text      string
+		ok        bool
+		positions []int
+	}{
+		// 空のパターンはどのテキストにも一致する
+		{"", "main.go", true, nil},
+		{"", "", true, nil},
+		// テキストより長いパターンは一致しない
+		{"main.go", "main", false, nil},
+		{"abc", "acb", false, nil},
+		{"fb", "foo/bar.go", true, []int{0, 4}},
+		// 先に出てくる文字より、区切りの直後の文字に一致させる
+		{"b", "abc/bar.go", true, []int{4}},
+		{"mg", "image/main.go", true, []int{6, 11}},
+		// 大文字を含まないパターンは大文字と小文字を区別しない
+		{"fb", "Foo/Bar.go", true, []int{0, 4}},
+		// 大文字を含むパターンは大文字と小文字を区別する
+		{"FB", "Foo/Bar.go", true, []int{0, 4}},
+		{"FB", "foo/bar.go", false, nil},
+		{"Fb", "Foo/Bar.go", false, nil},
+		// 位置はバイトではなく rune で数える
+		{"設定", "ドキュメント/設定.md", true, []int{7, 8}},
+		{"dm", "ドキュメント/設定.md", false, nil},
+		{"sm", "日本語/設定.md", false, nil},
+		{"md", "日本語/設定.md", true, []int{7, 8}},
+	}
+
+	for _, tt := range tests {
+		_, positions, ok := Match(tt.pattern, tt.text)
+		if ok != tt.ok {
+			t.Errorf("Match(%q, %q) ok = %v, want %v", tt.pattern, tt.text, ok, tt.ok)
+			continue
+		}
+		if !slices.Equal(positions, tt.positions) {
+			t.Errorf("Match(%q, %q) positions = %v, want %v", tt.pattern, tt.text, positions, tt.positions)
+		}
+	}
+}
+
+func TestMatchRanking(t *testing.T) {
+	tests := []struct {
+		pattern string
+		better  string
+		worse   string
+	}{
+		// パスの区切りの直後で一致するほうが、ばらばらに一致するより高い
+		{"fb", "foo/bar.go", "xfoxbar.go"},
+		{"main", "cmd/main.go", "my/ai/n.go"},
+		// 単語の区切りや camelCase の大文字で一致するほうが高い
+		{"fb", "x_foo_bar.go", "xfooxbar.go"},
+		{"fb", "xFooBar.go", "xfooxbar.go"},
+		// 連続して一致するほうが高い
+		{"bar", "xbarx.go", "xbxaxr.go"},
+		// ファイル名で一致するほうが高い
+		{"foo", "src/foo", "foo/src"},
+	}
+
+	for _, tt := range tests {
+		better, _, ok := Match(tt.pattern, tt.better)
+		if !ok {
+			t.Errorf("Match(%q, %q) did not match", tt.pattern, tt.better)
+			continue
+		}
+		worse, _, ok := Match(tt.pattern, tt.worse)
+		if !ok {
+			t.Errorf("Match(%q, %q) did not match", tt.pattern, tt.worse)
+			continue
+		}
+		if better <= worse {
+			t.Errorf("Match(%q): %q scored %d, want more than %q (%d)", tt.pattern, tt.better, better, tt.worse, worse)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BlameCommit は blame に含まれるコミットの情報
+type BlameCommit struct {
+	Hash       string
+	Author     string
+	AuthorTime time.Time
+	Summary    string
+}
+
+// ShortHash は省略したコミットハッシュを返す
+func (c *BlameCommit) ShortHash() string {
+	if len(c.Hash) > 7 {
+		return c.Hash[:7]
+	}
+	return c.Hash
+}
+
+// IsUncommitted はまだコミットされていない行かどうかを返す
+func (c *BlameCommit) IsUncommitted() bool {
+	return strings.Trim(c.Hash, "0") == ""
+}
+
+// BlameLine は blame の 1 行分
+type BlameLine struct {
+	Commit  *BlameCommit
+	Content string
+}
+
+// Blame は `git blame --porcelain` を実行して行ごとのコミット情報を返す
+// rev が空でなければそのリビジョン時点のファイルを対象にする
+func Blame(filePath string, rev string) ([]BlameLine, error) {
+	root, relPath, err := splitRepositoryPath(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"blame", "--porcelain"}
+	if rev != "" {
+		args = append(args, rev)
+	}
+	// blame に渡すのは pathspec ではなくファイルのパスなので、"*" などを含んでいても glob として解釈されない
+	args = append(args, "--", relPath)
+
+	output, err := runGit(root, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseBlame([]byte(output))
+}
+
+// parseBlame は `git blame --porcelain` の出力をパースする
+func parseBlame(output []byte) ([]BlameLine, error) {
+	commits := make(map[string]*BlameCommit)
+	var lines []BlameLine
+
+	var current *BlameCommit
+	var finalLine int
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, "\t") {
+			// 行の内容
+			if current == nil {
+				return nil, fmt.Errorf("unexpected blame content line")
+			}
+			for len(lines) < finalLine {
+				lines = append(lines, BlameLine{})
+			}
+			lines[finalLine-1] = BlameLine{Commit: current, Content: line[1:]}
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, " ")
+		if current == nil || (len(key) == 40 || len(key) == 64) && isHex(key) {
+			// "<hash> <orig line> <final line> [<num lines>]"
+			fields := strings.Fields(line)
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("malformed blame header: %s", line)
+			}
+			commit, ok := commits[fields[0]]
+			if !ok {
+				commit = &BlameCommit{Hash: fields[0]}
+				commits[fields[0]] = commit
+			}
+			current = commit
+			finalLine, _ = strconv.Atoi(fields[2])
+			if finalLine < 1 {
+				return nil, fmt.Errorf("malformed blame header: %s", line)
+			}
+			continue
+		}
+
+		switch key {
+		case "author":
+			current.Author = value
+		case "author-time":
+			if sec, err := strconv.ParseInt(value, 10, 64); err == nil {
+				current.AuthorTime = time.Unix(sec, 0)
+			}
+		case "summary":
+			current.Summary = value
+		}
+	}
+
+	return lines, scanner.Err()
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
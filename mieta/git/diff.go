@@ -0,0 +1,66 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+)
+
+// DiffTarget は差分の比較対象
+type DiffTarget int
+
+const (
+	// DiffAgainstHead はワーキングツリーと HEAD を比較する
+	DiffAgainstHead DiffTarget = iota
+	// DiffAgainstIndex はワーキングツリーとインデックスを比較する
+	DiffAgainstIndex
+)
+
+func (t DiffTarget) String() string {
+	if t == DiffAgainstIndex {
+		return "index"
+	}
+	return "HEAD"
+}
+
+// Diff は filePath の unified diff を返す。差分がない場合は空文字列を返す
+func Diff(filePath string, target DiffTarget) (string, error) {
+	args := []string{"diff", "--no-color", "--no-ext-diff"}
+	if target == DiffAgainstHead {
+		args = append(args, "HEAD")
+	}
+	args = append(args, "--", literalPathspec(filepath.Base(filePath)))
+
+	return runDiff(filepath.Dir(filePath), args)
+}
+
+// DiffUntracked は untracked なファイルを新規追加されたファイルとして diff 形式で返す
+func DiffUntracked(filePath string) (string, error) {
+	args := []string{"diff", "--no-color", "--no-ext-diff", "--no-index", "--", "/dev/null", filepath.Base(filePath)}
+	return runDiff(filepath.Dir(filePath), args)
+}
+
+// literalPathspec は path を glob として解釈させずに、そのままのパスとして指定する pathspec を返す
+// "*" や "?", "[" を含むファイル名が、ほかのファイルにマッチしないようにする
+func literalPathspec(path string) string {
+	return ":(literal)" + path
+}
+
+func runDiff(dir string, args []string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		// --no-index は差分があると exit status 1 を返す
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return string(output), nil
+		}
+		if exitErr != nil && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git diff failed: %s", exitErr.Stderr)
+		}
+		return "", fmt.Errorf("git diff failed: %w", err)
+	}
+	return string(output), nil
+}
@@ -1,74 +1,239 @@
 package git
 
 import (
-	"bufio"
 	"log"
-	"os/exec"
+	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
 // GitTracker は Git リポジトリのファイル追跡状態を管理するクラス
 type GitTracker struct {
-	ignoredFiles map[string]bool
+	mutex sync.Mutex
+	// core.excludesFile（グローバル設定）の値
+	globalExcludesFile string
+	// ワークツリーのルート -> リポジトリ
+	repositories map[string]*repository
+	// ディレクトリ -> そのディレクトリを含むリポジトリのルート（リポジトリ外なら ""）
+	repositoryRoots map[string]string
+	// 最後に取得した git status の結果
+	status *gitStatus
+}
+
+// repository は 1 つのワークツリーに関する ignore ルールのキャッシュ
+type repository struct {
+	root   string
+	gitDir string
+	// このリポジトリで使う core.excludesFile のパス。リポジトリの設定で上書きされていればそのパス
+	excludesFile string
+	// core.excludesFile と .git/info/exclude のパターン（優先度の低い順）
+	basePatterns []*ignorePattern
+	// ディレクトリ（ルートからの相対パス）-> そのディレクトリの .gitignore のパターン
+	dirPatterns map[string][]*ignorePattern
 }
 
 // NewGitTracker は GitTracker の新しいインスタンスを作成
 func NewGitTracker() *GitTracker {
 	tracker := &GitTracker{
-		ignoredFiles: make(map[string]bool),
+		repositories:    make(map[string]*repository),
+		repositoryRoots: make(map[string]string),
 	}
 
 	return tracker
 }
 
-// Initialize は Git リポジトリからファイル情報を初期化
+// Initialize はグローバルな git config から core.excludesFile を読み込む
 func (g *GitTracker) Initialize() error {
-	// Git コマンドが利用可能か確認
-	_, err := exec.LookPath("git")
-	if err != nil {
-		log.Printf("git not found in $PATH")
-		return nil
-	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
 
-	cmd := exec.Command("git", "ls-files", "--others", "--ignored", "--exclude-standard")
-	output, err := cmd.Output()
-	if err == nil {
-		scanner := bufio.NewScanner(strings.NewReader(string(output)))
-		for scanner.Scan() {
-			relPath := scanner.Text()
-			abs, err := filepath.Abs(relPath)
-			if err == nil {
-				log.Printf("Found ignored file: %s", abs)
-				g.ignoredFiles[abs] = true
-			} else {
-				log.Printf("Error for Abs: %s, %v", relPath, err)
-			}
+	g.globalExcludesFile = defaultExcludesFile()
+	for _, configPath := range globalConfigPaths() {
+		if value, ok := readConfigValue(configPath, "core", "excludesFile"); ok {
+			g.globalExcludesFile = expandHome(value)
 		}
 	}
+	log.Printf("Global excludes file: %s", g.globalExcludesFile)
 
 	return nil
 }
 
 // IsIgnored はファイルが Git で無視されているかを判定
-func (g *GitTracker) IsIgnored(filePath string) bool {
-	// 追跡されているファイルは無視されない
-	if g.ignoredFiles[filePath] {
-		log.Printf("Ignored file: %s", filePath)
-		return true
-	}
-
-	// .git ディレクトリは特別扱い
+func (g *GitTracker) IsIgnored(filePath string, isDir bool) bool {
 	absPath, err := filepath.Abs(filePath)
 	if err != nil {
 		log.Printf("Abs error: %s", filePath)
 		return false
 	}
+
+	// .git ディレクトリは特別扱い
 	if strings.Contains(absPath, "/.git/") || strings.HasSuffix(absPath, "/.git") {
-		log.Printf(".git: %s", filePath)
 		return true
 	}
 
-	log.Printf("not ignored: %s", filePath)
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	repo := g.repositoryFor(filepath.Dir(absPath))
+	if repo == nil {
+		return false
+	}
+
+	relPath, err := filepath.Rel(repo.root, absPath)
+	if err != nil || relPath == "." {
+		return false
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	// 親ディレクトリが無視されていれば、その中身も無視される
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		if repo.isIgnored(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+
+	return repo.isIgnored(relPath, isDir)
+}
+
+// InvalidateIgnoreRules は filePath が ignore ルールのファイルなら該当するキャッシュを破棄する
+// キャッシュを破棄した場合は true を返す
+func (g *GitTracker) InvalidateIgnoreRules(filePath string) bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	base := filepath.Base(filePath)
+	isExclude := base == "exclude" && filepath.Base(filepath.Dir(filePath)) == "info"
+	if base != ".gitignore" && !isExclude && filePath != g.globalExcludesFile && !g.isRepositoryConfigFile(filePath) {
+		return false
+	}
+
+	log.Printf("Ignore rules changed: %s", filePath)
+	g.repositories = make(map[string]*repository)
+	g.repositoryRoots = make(map[string]string)
+	return true
+}
+
+// isRepositoryConfigFile は filePath が読み込んだリポジトリの .git/config か、
+// そこで指定された core.excludesFile かを返す。mutex を取得した状態で呼ぶこと
+// .git/config が変わると core.excludesFile のパスも変わるので、読み込み直すためにキャッシュを破棄する
+func (g *GitTracker) isRepositoryConfigFile(filePath string) bool {
+	for _, repo := range g.repositories {
+		if filePath == repo.excludesFile || filePath == filepath.Join(resolveCommonDir(repo.gitDir), "config") {
+			return true
+		}
+	}
+	return false
+}
+
+// InfoExcludeFile は path を含むリポジトリの info/exclude のパスを返す。リポジトリ外なら "" を返す
+func (g *GitTracker) InfoExcludeFile(path string) string {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	repo := g.repositoryFor(path)
+	if repo == nil {
+		return ""
+	}
+	return filepath.Join(resolveCommonDir(repo.gitDir), "info", "exclude")
+}
+
+// repositoryFor は dir を含むリポジトリを返す。mutex を取得した状態で呼ぶこと
+func (g *GitTracker) repositoryFor(dir string) *repository {
+	root, ok := g.repositoryRoots[dir]
+	if !ok {
+		root = findRepositoryRoot(dir)
+		g.repositoryRoots[dir] = root
+	}
+	if root == "" {
+		return nil
+	}
+
+	if repo, ok := g.repositories[root]; ok {
+		return repo
+	}
+
+	repo := g.openRepository(root)
+	g.repositories[root] = repo
+	return repo
+}
+
+// openRepository はリポジトリの ignore ルールを読み込む
+func (g *GitTracker) openRepository(root string) *repository {
+	gitDir := resolveGitDir(root)
+	repo := &repository{
+		root:        root,
+		gitDir:      gitDir,
+		dirPatterns: make(map[string][]*ignorePattern),
+	}
+
+	// リポジトリの設定で core.excludesFile が上書きされている場合はそちらを使う
+	repo.excludesFile = g.globalExcludesFile
+	if value, ok := readConfigValue(filepath.Join(resolveCommonDir(gitDir), "config"), "core", "excludesFile"); ok {
+		repo.excludesFile = expandHome(value)
+	}
+
+	for _, ignoreFile := range []string{repo.excludesFile, filepath.Join(resolveCommonDir(gitDir), "info", "exclude")} {
+		if ignoreFile == "" {
+			continue
+		}
+		patterns, err := parseIgnoreFile(ignoreFile, "")
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Printf("Error reading %s: %v", ignoreFile, err)
+			}
+			continue
+		}
+		repo.basePatterns = append(repo.basePatterns, patterns...)
+	}
+
+	log.Printf("Opened git repository: %s (gitdir: %s)", root, gitDir)
+	return repo
+}
+
+// patternsFor はディレクトリ dir（ルートからの相対パス）の .gitignore を読み込む
+func (r *repository) patternsFor(dir string) []*ignorePattern {
+	if patterns, ok := r.dirPatterns[dir]; ok {
+		return patterns
+	}
+
+	ignoreFile := filepath.Join(r.root, filepath.FromSlash(dir), ".gitignore")
+	patterns, err := parseIgnoreFile(ignoreFile, dir)
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("Error reading %s: %v", ignoreFile, err)
+	}
+	r.dirPatterns[dir] = patterns
+	return patterns
+}
+
+// isIgnored は relPath 自体がパターンにマッチするかを判定する（親ディレクトリは見ない）
+func (r *repository) isIgnored(relPath string, isDir bool) bool {
+	// 深いディレクトリの .gitignore ほど優先度が高い
+	var dirs []string
+	for dir := path.Dir(relPath); ; dir = path.Dir(dir) {
+		if dir == "." {
+			dirs = append(dirs, "")
+			break
+		}
+		dirs = append(dirs, dir)
+	}
+
+	for _, dir := range dirs {
+		patterns := r.patternsFor(dir)
+		for i := len(patterns) - 1; i >= 0; i-- {
+			if patterns[i].match(relPath, isDir) {
+				return !patterns[i].negate
+			}
+		}
+	}
+
+	for i := len(r.basePatterns) - 1; i >= 0; i-- {
+		if r.basePatterns[i].match(relPath, isDir) {
+			return !r.basePatterns[i].negate
+		}
+	}
+
 	return false
 }
@@ -0,0 +1,171 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsIgnored(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".gitignore"), `# comment
+*.log
+!keep.log
+/build
+docs/**/*.tmp
+**/cache
+out/
+a/**/b
+\#hash
+trailing   
+`)
+	writeFile(t, filepath.Join(root, "sub", ".gitignore"), `*.txt
+!important.txt
+/local
+`)
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		// パターンはファイル名にマッチし、"!" で取り消せる
+		{"app.log", false, true},
+		{"sub/deep/app.log", false, true},
+		{"keep.log", false, false},
+		// 先頭の "/" はその .gitignore のディレクトリに固定する
+		{"build", true, true},
+		{"src/build", true, false},
+		// 無視されたディレクトリの中身は "!" があっても無視される
+		{"build/main.go", false, true},
+		{"build/keep.log", false, true},
+		// "**" は 0 個以上のディレクトリにマッチする
+		{"docs/x.tmp", false, true},
+		{"docs/a/b/x.tmp", false, true},
+		{"x.tmp", false, false},
+		{"cache", true, true},
+		{"src/cache", true, true},
+		{"a/b", true, true},
+		{"a/x/y/b", false, true},
+		{"a/x/y/c", false, false},
+		// 末尾の "/" はディレクトリにだけマッチする
+		{"out", true, true},
+		{"src/out", true, true},
+		{"out", false, false},
+		// エスケープと末尾の空白
+		{"#hash", false, true},
+		{"trailing", false, true},
+		{"comment", false, false},
+		// サブディレクトリの .gitignore はそのディレクトリからの相対パスで評価する
+		{"sub/notes.txt", false, true},
+		{"sub/important.txt", false, false},
+		{"notes.txt", false, false},
+		{"sub/local", true, true},
+		{"sub/x/local", true, false},
+		// .git の中は常に無視する
+		{".git/config", false, true},
+	}
+
+	tracker := NewGitTracker()
+	for _, tt := range tests {
+		got := tracker.IsIgnored(filepath.Join(root, filepath.FromSlash(tt.path)), tt.isDir)
+		if got != tt.want {
+			t.Errorf("IsIgnored(%q, isDir=%v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestParseIgnoreLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		wantNil  bool
+		negate   bool
+		dirOnly  bool
+		anchored bool
+	}{
+		{line: "", wantNil: true},
+		{line: "   ", wantNil: true},
+		{line: "# comment", wantNil: true},
+		{line: "/", wantNil: true},
+		{line: "*.o"},
+		{line: "!*.o", negate: true},
+		{line: `\!important`},
+		{line: "tmp/", dirOnly: true},
+		{line: "/tmp", anchored: true},
+		{line: "src/*.o", anchored: true},
+		{line: "!/out/", negate: true, dirOnly: true, anchored: true},
+	}
+
+	for _, tt := range tests {
+		pattern := parseIgnoreLine(tt.line, "")
+		if tt.wantNil {
+			if pattern != nil {
+				t.Errorf("parseIgnoreLine(%q) = %+v, want nil", tt.line, pattern)
+			}
+			continue
+		}
+		if pattern == nil {
+			t.Errorf("parseIgnoreLine(%q) = nil", tt.line)
+			continue
+		}
+		if pattern.negate != tt.negate || pattern.dirOnly != tt.dirOnly || pattern.anchored != tt.anchored {
+			t.Errorf("parseIgnoreLine(%q) = negate:%v dirOnly:%v anchored:%v, want negate:%v dirOnly:%v anchored:%v",
+				tt.line, pattern.negate, pattern.dirOnly, pattern.anchored, tt.negate, tt.dirOnly, tt.anchored)
+		}
+	}
+}
+
+func TestInvalidateIgnoreRules(t *testing.T) {
+	root := t.TempDir()
+	excludesA := filepath.Join(root, "excludes-a")
+	excludesB := filepath.Join(root, "excludes-b")
+	configFile := filepath.Join(root, ".git", "config")
+	writeFile(t, excludesA, "*.log\n")
+	writeFile(t, excludesB, "*.tmp\n")
+	writeFile(t, configFile, "[core]\n\texcludesFile = "+excludesA+"\n")
+
+	tracker := NewGitTracker()
+	check := func(name string, want bool) {
+		t.Helper()
+		if got := tracker.IsIgnored(filepath.Join(root, name), false); got != want {
+			t.Errorf("IsIgnored(%q) = %v, want %v", name, got, want)
+		}
+	}
+	check("app.log", true)
+	check("app.tmp", false)
+
+	// リポジトリの設定で指定した core.excludesFile の変更を読み直す
+	writeFile(t, excludesA, "*.log\n*.tmp\n")
+	if !tracker.InvalidateIgnoreRules(excludesA) {
+		t.Errorf("InvalidateIgnoreRules(%q) = false, want true", excludesA)
+	}
+	check("app.tmp", true)
+
+	// .git/config が変わったら core.excludesFile のパスを読み直す
+	writeFile(t, configFile, "[core]\n\texcludesFile = "+excludesB+"\n")
+	if !tracker.InvalidateIgnoreRules(configFile) {
+		t.Errorf("InvalidateIgnoreRules(%q) = false, want true", configFile)
+	}
+	check("app.log", false)
+	check("app.tmp", true)
+
+	// 使われなくなった excludes ファイルや関係のないファイルでは破棄しない
+	for _, path := range []string{excludesA, filepath.Join(root, "main.go")} {
+		if tracker.InvalidateIgnoreRules(path) {
+			t.Errorf("InvalidateIgnoreRules(%q) = true, want false", path)
+		}
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,136 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// LogEntry はファイルの履歴に含まれる 1 コミット分の情報
+type LogEntry struct {
+	Hash    string
+	Author  string
+	Date    string
+	Subject string
+	// そのコミット時点でのファイルのパス（リポジトリルートからの相対パス）
+	Path string
+}
+
+// ShortHash は省略したコミットハッシュを返す
+func (e *LogEntry) ShortHash() string {
+	if len(e.Hash) > 7 {
+		return e.Hash[:7]
+	}
+	return e.Hash
+}
+
+// FileHistory は `git log --follow` でファイルを変更したコミットの一覧を返す
+func FileHistory(filePath string) ([]LogEntry, error) {
+	root, relPath, err := splitRepositoryPath(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := runGit(root,
+		"log", "--follow", "--name-only", "--date=short",
+		"--format=%x1e%H%x1f%an%x1f%ad%x1f%s",
+		"--", literalPathspec(relPath))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []LogEntry
+	// マージコミットにはファイル名の行がないので、一つ新しいコミットのパスを引き継ぐ
+	path := relPath
+	for _, record := range strings.Split(output, "\x1e") {
+		lines := strings.Split(strings.TrimSpace(record), "\n")
+		fields := strings.Split(lines[0], "\x1f")
+		if len(fields) < 4 {
+			continue
+		}
+
+		entry := LogEntry{
+			Hash:    fields[0],
+			Author:  fields[1],
+			Date:    fields[2],
+			Subject: fields[3],
+		}
+		for _, line := range lines[1:] {
+			if line != "" {
+				path = line
+				break
+			}
+		}
+		entry.Path = path
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// LastCommit は rev（空なら HEAD）までで filePath を最後に変更したコミットを返す
+// まだコミットされていないファイルの場合は nil を返す
+func LastCommit(filePath string, rev string) (*LogEntry, error) {
+	root, relPath, err := splitRepositoryPath(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"log", "-1", "--date=short", "--format=%H%x1f%an%x1f%ad%x1f%s"}
+	if rev != "" {
+		args = append(args, rev)
+	}
+	args = append(args, "--", literalPathspec(relPath))
+	output, err := runGit(root, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Split(strings.TrimSpace(output), "\x1f")
+	if len(fields) < 4 {
+		return nil, nil
+	}
+	return &LogEntry{
+		Hash:    fields[0],
+		Author:  fields[1],
+		Date:    fields[2],
+		Subject: fields[3],
+		Path:    relPath,
+	}, nil
+}
+
+// ShowFile は rev 時点でのファイルの内容を返す
+// relPath はリポジトリルートからの相対パス、dir はリポジトリ内の任意のディレクトリ
+func ShowFile(dir string, rev string, relPath string) ([]byte, error) {
+	output, err := runGit(dir, "show", rev+":"+relPath)
+	return []byte(output), err
+}
+
+// ShowFileAtRevision は rev 時点での filePath の内容を返す
+func ShowFileAtRevision(filePath string, rev string) ([]byte, error) {
+	root, relPath, err := splitRepositoryPath(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return ShowFile(root, rev, relPath)
+}
+
+// ShowCommitDiff は rev のコミットで relPath に加えられた変更を diff 形式で返す
+func ShowCommitDiff(dir string, rev string, relPath string) (string, error) {
+	return runGit(dir, "show", "--no-color", "--no-ext-diff", "--format=", rev, "--", ":(top,literal)"+relPath)
+}
+
+// runGit は dir で git コマンドを実行して標準出力を返す
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s failed: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return string(output), nil
+}
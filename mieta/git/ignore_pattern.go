@@ -0,0 +1,159 @@
+package git
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ignorePattern は .gitignore の 1 行分のパターン
+type ignorePattern struct {
+	// パターンが定義されたディレクトリ（リポジトリルートからの相対パス。ルートは ""）
+	base string
+	// "!" で始まるパターン（除外の取り消し）
+	negate bool
+	// "/" で終わるパターン（ディレクトリにのみマッチ）
+	dirOnly bool
+	// "/" を含むパターンは base からの相対パス全体にマッチさせる
+	anchored bool
+	re       *regexp.Regexp
+}
+
+// parseIgnoreFile は ignore ファイルを読み込んでパターンの一覧を返す
+func parseIgnoreFile(path string, base string) ([]*ignorePattern, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var patterns []*ignorePattern
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if pattern := parseIgnoreLine(scanner.Text(), base); pattern != nil {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns, scanner.Err()
+}
+
+// parseIgnoreLine は 1 行をパースする。空行やコメントの場合は nil を返す
+func parseIgnoreLine(line string, base string) *ignorePattern {
+	line = strings.TrimSuffix(line, "\r")
+	line = trimTrailingSpaces(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+
+	pattern := &ignorePattern{base: base}
+	if strings.HasPrefix(line, "!") {
+		pattern.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		pattern.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return nil
+	}
+
+	// 先頭や途中に "/" があるパターンは .gitignore の場所からの相対パスとして扱う
+	if strings.Contains(line, "/") {
+		pattern.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+
+	re, err := regexp.Compile("^" + globToRegexp(line) + "$")
+	if err != nil {
+		return nil
+	}
+	pattern.re = re
+	return pattern
+}
+
+// trimTrailingSpaces はエスケープされていない末尾の空白を取り除く
+func trimTrailingSpaces(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+// globToRegexp は gitignore のグロブを正規表現に変換する
+func globToRegexp(glob string) string {
+	var buf strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				// "**" の扱い: 先頭の "**/", 末尾の "/**", 途中の "/**/"
+				atStart := i == 0 || glob[i-1] == '/'
+				atEnd := i+2 == len(glob) || glob[i+2] == '/'
+				if atStart && atEnd {
+					i++
+					if i+1 < len(glob) {
+						// "**/" は 0 個以上のディレクトリにマッチ
+						i++
+						buf.WriteString("(?:.*/)?")
+					} else {
+						buf.WriteString(".*")
+					}
+					continue
+				}
+			}
+			buf.WriteString("[^/]*")
+		case '?':
+			buf.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				buf.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			buf.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				buf.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return buf.String()
+}
+
+// match は relPath（リポジトリルートからの相対パス）がこのパターンにマッチするか判定する
+func (p *ignorePattern) match(relPath string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+
+	target := relPath
+	if p.base != "" {
+		if !strings.HasPrefix(relPath, p.base+"/") {
+			return false
+		}
+		target = relPath[len(p.base)+1:]
+	}
+
+	if !p.anchored {
+		// "/" を含まないパターンはファイル名だけにマッチさせる
+		if idx := strings.LastIndexByte(target, '/'); idx >= 0 {
+			target = target[idx+1:]
+		}
+	}
+
+	return p.re.MatchString(target)
+}
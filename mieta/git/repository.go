@@ -0,0 +1,165 @@
+package git
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// findRepositoryRoot は path を含む Git リポジトリのワークツリーのルートを探す
+// リポジトリに含まれない場合は "" を返す
+func findRepositoryRoot(path string) string {
+	dir := path
+	for {
+		if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// RepositoryRoot は path を含むリポジトリのワークツリーのルートを返す
+func RepositoryRoot(path string) string {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	return findRepositoryRoot(absPath)
+}
+
+// splitRepositoryPath は filePath をリポジトリのルートとルートからの相対パスに分ける
+// リビジョンを表示している場合はディレクトリがワーキングツリーに存在しないこともあるので、
+// git コマンドはリポジトリのルートで実行する
+func splitRepositoryPath(filePath string) (root string, relPath string, err error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", "", err
+	}
+
+	root = findRepositoryRoot(absPath)
+	if root == "" {
+		return "", "", fmt.Errorf("not a git repository: %s", filePath)
+	}
+
+	relPath, err = filepath.Rel(root, absPath)
+	if err != nil {
+		return "", "", err
+	}
+	return root, filepath.ToSlash(relPath), nil
+}
+
+// resolveGitDir はワークツリーのルートから .git ディレクトリの実体を返す
+// worktree や submodule では .git が "gitdir: ..." を含むファイルになっている
+func resolveGitDir(root string) string {
+	dotGit := filepath.Join(root, ".git")
+	info, err := os.Stat(dotGit)
+	if err != nil || info.IsDir() {
+		return dotGit
+	}
+
+	content, err := os.ReadFile(dotGit)
+	if err != nil {
+		return dotGit
+	}
+	line := strings.TrimSpace(string(content))
+	if !strings.HasPrefix(line, "gitdir:") {
+		return dotGit
+	}
+	gitDir := strings.TrimSpace(strings.TrimPrefix(line, "gitdir:"))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(root, gitDir)
+	}
+	return filepath.Clean(gitDir)
+}
+
+// resolveCommonDir は worktree の場合に共有される git ディレクトリを返す
+func resolveCommonDir(gitDir string) string {
+	content, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	commonDir := strings.TrimSpace(string(content))
+	if !filepath.IsAbs(commonDir) {
+		commonDir = filepath.Join(gitDir, commonDir)
+	}
+	return filepath.Clean(commonDir)
+}
+
+// globalConfigPaths はグローバルな git config ファイルの候補を優先度の低い順に返す
+func globalConfigPaths() []string {
+	var paths []string
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		paths = append(paths, filepath.Join(xdg, "git", "config"))
+	} else if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".config", "git", "config"))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".gitconfig"))
+	}
+	return paths
+}
+
+// defaultExcludesFile は core.excludesFile が未設定の場合に使われるファイル
+func defaultExcludesFile() string {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "git", "ignore")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".config", "git", "ignore")
+	}
+	return ""
+}
+
+// readConfigValue は git config ファイルから section.key の値を読み取る
+// 見つからない場合は ok が false になる
+func readConfigValue(path string, section string, key string) (value string, ok bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer file.Close()
+
+	currentSection := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			currentSection = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			continue
+		}
+		if currentSection != strings.ToLower(section) {
+			continue
+		}
+
+		name, val, found := strings.Cut(line, "=")
+		if !found || !strings.EqualFold(strings.TrimSpace(name), key) {
+			continue
+		}
+		val = strings.TrimSpace(val)
+		val = strings.Trim(val, `"`)
+		// 後勝ちなので最後まで読む
+		value, ok = val, true
+	}
+	return value, ok
+}
+
+// expandHome は先頭の "~/" をホームディレクトリに展開する
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
@@ -0,0 +1,169 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"maps"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// FileStatus はワーキングツリー上のファイルの状態を表すビットフラグ
+type FileStatus int
+
+const (
+	StatusModified FileStatus = 1 << iota
+	StatusStaged
+	StatusUntracked
+	StatusDeleted
+	StatusConflicted
+	StatusRenamed
+)
+
+// gitStatus は RefreshStatus で取得したリポジトリの状態
+type gitStatus struct {
+	root string
+	// ファイルの絶対パス -> 状態
+	files map[string]FileStatus
+	// ディレクトリの絶対パス -> 配下のファイルの状態をまとめたもの
+	dirs map[string]FileStatus
+	// 中身ごと untracked なディレクトリ（"?? dir/" と報告されたもの）
+	untrackedDirs []string
+}
+
+// RefreshStatus は rootDir を含むリポジトリの `git status` を取得し直す
+// 前回取得したときから状態が変わったかどうかを返す
+func (g *GitTracker) RefreshStatus(rootDir string) (bool, error) {
+	repoRoot := findRepositoryRoot(rootDir)
+	if repoRoot == "" {
+		// リポジトリの外に移った場合は、前のリポジトリの状態を残さない
+		g.mutex.Lock()
+		defer g.mutex.Unlock()
+		changed := g.status != nil
+		g.status = nil
+		return changed, nil
+	}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		return false, fmt.Errorf("git not found in $PATH")
+	}
+
+	cmd := exec.Command("git", "status", "--porcelain=v1", "-z", "--untracked-files=normal")
+	cmd.Dir = repoRoot
+	output, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("git status failed: %w", err)
+	}
+
+	status := parseStatus(repoRoot, output)
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	changed := !status.equal(g.status)
+	g.status = status
+
+	log.Printf("Refreshed git status: %s (%d entries)", repoRoot, len(status.files))
+	return changed, nil
+}
+
+// equal は other と同じ状態かどうかを返す
+func (s *gitStatus) equal(other *gitStatus) bool {
+	return other != nil && s.root == other.root &&
+		maps.Equal(s.files, other.files) &&
+		maps.Equal(s.dirs, other.dirs) &&
+		slices.Equal(s.untrackedDirs, other.untrackedDirs)
+}
+
+// parseStatus は `git status --porcelain=v1 -z` の出力をパースする
+func parseStatus(repoRoot string, output []byte) *gitStatus {
+	status := &gitStatus{
+		root:  repoRoot,
+		files: make(map[string]FileStatus),
+		dirs:  make(map[string]FileStatus),
+	}
+
+	entries := bytes.Split(output, []byte{0})
+	for i := 0; i < len(entries); i++ {
+		entry := string(entries[i])
+		if len(entry) < 4 {
+			continue
+		}
+		x, y, relPath := entry[0], entry[1], entry[3:]
+
+		var flags FileStatus
+		switch {
+		case x == '?' && y == '?':
+			flags = StatusUntracked
+		case x == '!' && y == '!':
+			continue
+		case x == 'U' || y == 'U' || (x == 'A' && y == 'A') || (x == 'D' && y == 'D'):
+			flags = StatusConflicted
+		default:
+			if x != ' ' {
+				flags |= StatusStaged
+			}
+			if x == 'R' || x == 'C' {
+				flags |= StatusRenamed
+			}
+			if y == 'M' {
+				flags |= StatusModified
+			}
+			if x == 'D' || y == 'D' {
+				flags |= StatusDeleted
+			}
+		}
+
+		if x == 'R' || x == 'C' {
+			// リネームの場合は次のエントリに元のパスが入っている
+			i++
+		}
+
+		absPath := filepath.Join(repoRoot, filepath.FromSlash(strings.TrimSuffix(relPath, "/")))
+		if strings.HasSuffix(relPath, "/") {
+			status.untrackedDirs = append(status.untrackedDirs, absPath)
+		}
+		status.files[absPath] |= flags
+
+		// 親ディレクトリに状態を伝播させる
+		for dir := filepath.Dir(absPath); strings.HasPrefix(dir, repoRoot) && dir != repoRoot; dir = filepath.Dir(dir) {
+			status.dirs[dir] |= flags
+		}
+		status.dirs[repoRoot] |= flags
+	}
+
+	return status
+}
+
+// Status はファイルの状態を返す。ディレクトリの場合は配下のファイルの状態をまとめたものを返す
+func (g *GitTracker) Status(filePath string) FileStatus {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.status == nil {
+		return 0
+	}
+
+	result := g.status.files[filePath] | g.status.dirs[filePath]
+	for _, dir := range g.status.untrackedDirs {
+		if strings.HasPrefix(filePath, dir+string(filepath.Separator)) {
+			result |= StatusUntracked
+			break
+		}
+	}
+	return result
+}
+
+// GitDir は path を含むリポジトリの .git ディレクトリを返す。リポジトリ外なら "" を返す
+func (g *GitTracker) GitDir(path string) string {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	repo := g.repositoryFor(path)
+	if repo == nil {
+		return ""
+	}
+	return repo.gitDir
+}
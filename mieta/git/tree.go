@@ -0,0 +1,116 @@
+package git
+
+import (
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TreeEntry は `git ls-tree` の 1 エントリ
+// ワーキングツリーのディレクトリと同じように扱えるよう fs.DirEntry と fs.FileInfo を実装する
+type TreeEntry struct {
+	name string
+	mode fs.FileMode
+	size int64
+}
+
+func (e *TreeEntry) Name() string               { return e.name }
+func (e *TreeEntry) IsDir() bool                { return e.mode.IsDir() }
+func (e *TreeEntry) Type() fs.FileMode          { return e.mode.Type() }
+func (e *TreeEntry) Info() (fs.FileInfo, error) { return e, nil }
+func (e *TreeEntry) Size() int64                { return e.size }
+func (e *TreeEntry) Mode() fs.FileMode          { return e.mode }
+func (e *TreeEntry) ModTime() time.Time         { return time.Time{} }
+func (e *TreeEntry) Sys() any                   { return nil }
+
+// ResolveRevision は rev をコミットハッシュに解決する
+func ResolveRevision(dir string, rev string) (string, error) {
+	output, err := runGit(dir, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// ListTree は rev 時点での dirPath 直下のエントリを返す
+func ListTree(dirPath string, rev string) ([]fs.DirEntry, error) {
+	root, relPath, err := splitRepositoryPath(dirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"ls-tree", "-z", "-l", rev}
+	if relPath != "." {
+		args = append(args, "--", relPath+"/")
+	}
+	output, err := runGit(root, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []fs.DirEntry
+	for _, record := range strings.Split(output, "\x00") {
+		// "<mode> SP <type> SP <object> SP+ <size> TAB <file>"
+		meta, name, found := strings.Cut(record, "\t")
+		if !found {
+			continue
+		}
+		fields := strings.Fields(meta)
+		if len(fields) < 4 {
+			continue
+		}
+
+		entry := &TreeEntry{name: path.Base(name)}
+		gitMode, _ := strconv.ParseUint(fields[0], 8, 32)
+		switch fields[1] {
+		case "tree", "commit":
+			// submodule はディレクトリとして扱う
+			entry.mode = fs.ModeDir | 0755
+		default:
+			if gitMode&0170000 == 0120000 {
+				entry.mode = fs.ModeSymlink | 0777
+			} else {
+				entry.mode = fs.FileMode(gitMode & 0777)
+			}
+			entry.size, _ = strconv.ParseInt(fields[3], 10, 64)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// ListFiles は rev 時点で dirPath 以下にあるすべてのファイルを、dirPath からの相対パス（"/" 区切り）で返す
+// submodule の中身は含まない
+func ListFiles(dirPath string, rev string) ([]string, error) {
+	root, relPath, err := splitRepositoryPath(dirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"ls-tree", "-r", "-z", rev}
+	prefix := ""
+	if relPath != "." {
+		prefix = relPath + "/"
+		args = append(args, "--", prefix)
+	}
+	output, err := runGit(root, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, record := range strings.Split(output, "\x00") {
+		// "<mode> SP <type> SP <object> TAB <file>"
+		meta, name, found := strings.Cut(record, "\t")
+		if !found {
+			continue
+		}
+		if fields := strings.Fields(meta); len(fields) < 2 || fields[1] != "blob" {
+			continue
+		}
+		files = append(files, strings.TrimPrefix(name, prefix))
+	}
+	return files, nil
+}
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
+	"github.com/tokuhirom/mieta/mieta/bookmark_view"
 	"github.com/tokuhirom/mieta/mieta/config"
 	"github.com/tokuhirom/mieta/mieta/files_view"
+	"github.com/tokuhirom/mieta/mieta/history_view"
 	"github.com/tokuhirom/mieta/mieta/search_view"
 )
 
@@ -36,6 +38,12 @@ func generateHelpMessage(config *config.Config) string {
 	keymap, _, _ = search_view.GetSearchKeymap(config)
 	buf += "\n\n# Search\n" + helpFoo("Search", keymap)
 
+	keymap, _, _ = history_view.GetHistoryKeymap(config)
+	buf += "\n\n# History\n" + helpFoo("History", keymap)
+
+	keymap, _, _ = bookmark_view.GetBookmarkKeymap(config)
+	buf += "\n\n# Bookmarks\n" + helpFoo("Bookmark", keymap)
+
 	keymap, _, _ = GetHelpKeymap(config)
 	buf += "\n\n# Help\n" + helpFoo("Help", keymap)
 
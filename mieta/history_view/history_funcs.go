@@ -0,0 +1,56 @@
+package history_view
+
+import "github.com/rivo/tview"
+
+// HistoryExitView は履歴ページを閉じます
+func HistoryExitView(view *HistoryView) {
+	view.Pages.HidePage("history")
+}
+
+// HistoryPreviousItem は前のコミットを表示します
+func HistoryPreviousItem(view *HistoryView) {
+	index := view.CommitList.GetCurrentItem()
+	if index > 0 {
+		view.CommitList.SetCurrentItem(index - 1)
+	}
+}
+
+// HistoryNextItem は次のコミットを表示します
+func HistoryNextItem(view *HistoryView) {
+	index := view.CommitList.GetCurrentItem()
+	if index < view.CommitList.GetItemCount()-1 {
+		view.CommitList.SetCurrentItem(index + 1)
+	}
+}
+
+// HistoryScrollDown はプレビューを下にスクロールします
+func HistoryScrollDown(view *HistoryView) {
+	row, col := view.ContentView.GetScrollOffset()
+	view.ContentView.ScrollTo(row+9, col)
+}
+
+// HistoryScrollUp はプレビューを上にスクロールします
+func HistoryScrollUp(view *HistoryView) {
+	row, col := view.ContentView.GetScrollOffset()
+	view.ContentView.ScrollTo(row-9, col)
+}
+
+// HistoryToggleDiff はその時点のファイルとコミットで加えられた変更の表示を切り替えます
+func HistoryToggleDiff(view *HistoryView) {
+	view.ShowDiff = !view.ShowDiff
+	view.reloadItem()
+}
+
+// HistoryDecreaseLeftWidth は左パネルの幅を減らします
+func HistoryDecreaseLeftWidth(view *HistoryView) {
+	leftFlex := view.Flex.GetItem(0).(*tview.Flex)
+	_, _, width, _ := leftFlex.GetRect()
+	view.Flex.ResizeItem(leftFlex, width-2, 1)
+}
+
+// HistoryIncreaseLeftWidth は左パネルの幅を増やします
+func HistoryIncreaseLeftWidth(view *HistoryView) {
+	leftFlex := view.Flex.GetItem(0).(*tview.Flex)
+	_, _, width, _ := leftFlex.GetRect()
+	view.Flex.ResizeItem(leftFlex, width+2, 1)
+}
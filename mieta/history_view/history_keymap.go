@@ -0,0 +1,36 @@
+package history_view
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/tokuhirom/mieta/mieta/config"
+	"github.com/tokuhirom/mieta/mieta/keymap"
+)
+
+type HistoryViewHandler func(view *HistoryView)
+
+var HistoryFunctions = map[string]HistoryViewHandler{
+	"HistoryExitView":          HistoryExitView,
+	"HistoryPreviousItem":      HistoryPreviousItem,
+	"HistoryNextItem":          HistoryNextItem,
+	"HistoryScrollDown":        HistoryScrollDown,
+	"HistoryScrollUp":          HistoryScrollUp,
+	"HistoryToggleDiff":        HistoryToggleDiff,
+	"HistoryDecreaseLeftWidth": HistoryDecreaseLeftWidth,
+	"HistoryIncreaseLeftWidth": HistoryIncreaseLeftWidth,
+}
+
+var DefaultKeyMap = map[string]string{
+	"Esc": "HistoryExitView",
+	"q":   "HistoryExitView",
+	"w":   "HistoryPreviousItem",
+	"s":   "HistoryNextItem",
+	"j":   "HistoryScrollDown",
+	"k":   "HistoryScrollUp",
+	"D":   "HistoryToggleDiff",
+	"H":   "HistoryDecreaseLeftWidth",
+	"L":   "HistoryIncreaseLeftWidth",
+}
+
+func GetHistoryKeymap(config *config.Config) (map[string]string, map[tcell.Key]HistoryViewHandler, map[rune]HistoryViewHandler) {
+	return keymap.ProcessKeymap("history", DefaultKeyMap, config.HistoryKeyMap, HistoryFunctions)
+}
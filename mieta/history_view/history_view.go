@@ -0,0 +1,195 @@
+package history_view
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/tokuhirom/mieta/mieta/config"
+	"github.com/tokuhirom/mieta/mieta/files_view"
+	"github.com/tokuhirom/mieta/mieta/git"
+	"log"
+	"path/filepath"
+	"unicode/utf8"
+)
+
+// HistoryView はファイルを変更したコミットの一覧と、その時点のファイルを表示するビュー
+type HistoryView struct {
+	Application *tview.Application
+	Config      *config.Config
+	Pages       *tview.Pages
+	Flex        *tview.Flex
+	CommitList  *tview.List
+	ContentView *tview.TextView
+	// 履歴を表示しているファイル
+	FilePath string
+	Entries  []git.LogEntry
+	// コミットで加えられた変更を表示するかどうか
+	ShowDiff bool
+
+	// 最後に読み込みを開始したリビジョン。古い読み込み結果を捨てるために使う
+	currentLoading string
+}
+
+// NewHistoryView creates a new history view
+func NewHistoryView(app *tview.Application, config *config.Config, pages *tview.Pages) *HistoryView {
+	commitList := tview.NewList().
+		ShowSecondaryText(true)
+	commitList.SetBorder(true)
+	commitList.SetBorderColor(tcell.ColorDarkSlateGray)
+	commitList.SetTitle("History")
+
+	contentView := tview.NewTextView().
+		SetDynamicColors(true).
+		SetWrap(true)
+	contentView.SetBorder(true)
+	contentView.SetBorderColor(tcell.ColorDarkSlateGray)
+	contentView.SetBorderPadding(0, 0, 1, 1)
+
+	statusBar := tview.NewTextView().
+		SetDynamicColors(true).
+		SetTextAlign(tview.AlignLeft)
+	statusBar.SetText("[yellow]D[white]: Toggle Diff | [yellow]Esc[white]: Exit History")
+
+	leftFlex := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(commitList, 0, 1, true).
+		AddItem(statusBar, 1, 0, false)
+
+	flex := tview.NewFlex().
+		AddItem(leftFlex, 0, 1, true).
+		AddItem(tview.NewBox(), 1, 0, false).
+		AddItem(contentView, 0, 2, false)
+
+	historyView := &HistoryView{
+		Application: app,
+		Config:      config,
+		Pages:       pages,
+		Flex:        flex,
+		CommitList:  commitList,
+		ContentView: contentView,
+	}
+
+	commitList.SetChangedFunc(func(index int, mainText string, secondaryText string, shortcut rune) {
+		historyView.ShowItem(index)
+	})
+
+	_, keycodeKeymap, runeKeymap := GetHistoryKeymap(config)
+	commitList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if handler, ok := keycodeKeymap[event.Key()]; ok {
+			handler(historyView)
+			return nil
+		}
+
+		if event.Key() == tcell.KeyRune {
+			if handler, ok := runeKeymap[event.Rune()]; ok {
+				handler(historyView)
+				return nil
+			}
+		}
+		return event
+	})
+
+	return historyView
+}
+
+// Open は filePath の履歴ページを表示して、コミットの一覧を非同期に読み込む
+func (h *HistoryView) Open(filePath string) {
+	h.FilePath = filePath
+	h.Entries = nil
+	h.currentLoading = ""
+	h.CommitList.Clear()
+	h.CommitList.SetTitle(fmt.Sprintf("History: %s", filepath.Base(filePath)))
+	h.ContentView.SetTitle(filePath)
+	h.ContentView.SetText("[blue]Loading...")
+	h.Pages.ShowPage("history")
+
+	go func() {
+		entries, err := git.FileHistory(filePath)
+		h.Application.QueueUpdateDraw(func() {
+			if h.FilePath != filePath {
+				return
+			}
+			if err != nil {
+				h.ContentView.SetText(fmt.Sprintf("[red]%s", tview.Escape(err.Error())))
+				return
+			}
+			if len(entries) == 0 {
+				h.ContentView.SetText("[yellow]No history")
+				return
+			}
+
+			h.Entries = entries
+			for _, entry := range entries {
+				h.CommitList.AddItem(
+					fmt.Sprintf("[yellow]%s[-] %s", entry.ShortHash(), tview.Escape(entry.Subject)),
+					fmt.Sprintf("%s %s", entry.Date, tview.Escape(entry.Author)),
+					0,
+					nil,
+				)
+			}
+			h.CommitList.SetTitle(fmt.Sprintf("History: %s (%d)", filepath.Base(filePath), len(entries)))
+			// AddItem で changed func が呼ばれないこともあるので明示的に表示する
+			h.ShowItem(h.CommitList.GetCurrentItem())
+		})
+	}()
+}
+
+// ShowItem は index 番目のコミット時点のファイル、またはコミットの diff を表示する
+func (h *HistoryView) ShowItem(index int) {
+	if index < 0 || index >= len(h.Entries) {
+		return
+	}
+	entry := h.Entries[index]
+
+	loadingKey := fmt.Sprintf("%s:%s:%v", entry.Hash, entry.Path, h.ShowDiff)
+	if h.currentLoading == loadingKey {
+		return
+	}
+	h.currentLoading = loadingKey
+
+	title := fmt.Sprintf("%s @ %s", entry.Path, entry.ShortHash())
+	if h.ShowDiff {
+		title += " (diff)"
+	}
+	h.ContentView.SetTitle(title)
+	h.ContentView.SetText("[blue]Loading...")
+
+	// リビジョンの表示中はファイルのディレクトリがワーキングツリーに存在しないこともある
+	dir := git.RepositoryRoot(h.FilePath)
+	showDiff := h.ShowDiff
+	go func() {
+		var text string
+		if showDiff {
+			diff, err := git.ShowCommitDiff(dir, entry.Hash, entry.Path)
+			if err != nil {
+				text = fmt.Sprintf("[red]%s", tview.Escape(err.Error()))
+			} else {
+				text, _ = files_view.RenderDiff(diff)
+			}
+		} else {
+			content, err := git.ShowFile(dir, entry.Hash, entry.Path)
+			if err != nil {
+				text = fmt.Sprintf("[red]%s", tview.Escape(err.Error()))
+			} else if !utf8.Valid(content) {
+				text = "[red]Binary"
+			} else {
+				text = files_view.HighlightContent(h.Config, entry.Path, string(content))
+			}
+		}
+
+		h.Application.QueueUpdateDraw(func() {
+			if h.currentLoading != loadingKey {
+				log.Printf("Ignoring revision: %s", loadingKey)
+				return
+			}
+			h.ContentView.SetText(text)
+			h.ContentView.ScrollToBeginning()
+		})
+	}()
+}
+
+// reloadItem は表示中のコミットを読み込み直す
+func (h *HistoryView) reloadItem() {
+	h.currentLoading = ""
+	h.ShowItem(h.CommitList.GetCurrentItem())
+}
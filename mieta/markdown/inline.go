@@ -0,0 +1,268 @@
+package markdown
+
+import (
+	"github.com/rivo/tview"
+	"strings"
+	"unicode"
+)
+
+// style は文字の色と属性
+type style struct {
+	color     string
+	bold      bool
+	italic    bool
+	underline bool
+	strike    bool
+}
+
+// tag は style を表す tview の色タグを返す
+func (s style) tag() string {
+	color := s.color
+	if color == "" {
+		color = "-"
+	}
+	attrs := ""
+	if s.bold {
+		attrs += "b"
+	}
+	if s.italic {
+		attrs += "i"
+	}
+	if s.underline {
+		attrs += "u"
+	}
+	if s.strike {
+		attrs += "s"
+	}
+	if attrs == "" {
+		attrs = "-"
+	}
+	return "[" + color + "::" + attrs + "]"
+}
+
+// renderInline は強調やコード、リンクを色タグにする。base は行全体の文字の色と属性
+// 最後に色と属性を元に戻すので、次の行には影響しない
+func (r *renderer) renderInline(text string, base style) string {
+	return r.inline(text, base) + style{}.tag()
+}
+
+// inline は renderInline の本体。最後に色と属性を元に戻さない
+func (r *renderer) inline(text string, base style) string {
+	var out strings.Builder
+	var literal strings.Builder
+	var bold, italic, strike bool
+
+	current := func() style {
+		s := base
+		s.bold = s.bold || bold
+		s.italic = s.italic || italic
+		s.strike = s.strike || strike
+		return s
+	}
+	// 色タグを含む文字列を書く前に、それまでの文字をエスケープして書き出す
+	emit := func(tagged string) {
+		out.WriteString(tview.Escape(literal.String()))
+		literal.Reset()
+		out.WriteString(tagged)
+	}
+
+	if base != (style{}) {
+		emit(base.tag())
+	}
+	for i := 0; i < len(text); {
+		c := text[i]
+		rest := text[i:]
+
+		switch {
+		case c == '\\' && i+1 < len(text) && isPunct(text[i+1]):
+			literal.WriteByte(text[i+1])
+			i += 2
+			continue
+
+		case c == '`':
+			delim := rest[:runLength(rest, '`')]
+			end := strings.Index(rest[len(delim):], delim)
+			if end < 0 {
+				literal.WriteString(delim)
+				i += len(delim)
+				continue
+			}
+			code := rest[len(delim) : len(delim)+end]
+			if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' {
+				code = code[1 : len(code)-1]
+			}
+			codeStyle := current()
+			codeStyle.color = "orange"
+			emit(codeStyle.tag())
+			literal.WriteString(code)
+			emit(current().tag())
+			i += 2*len(delim) + end
+			continue
+
+		case c == '!' || c == '[':
+			image := c == '!'
+			start := rest
+			if image {
+				start = rest[1:]
+			}
+			label, target, n, ok := parseLink(start)
+			if !ok {
+				break
+			}
+			if image {
+				imageStyle := current()
+				imageStyle.color = "gray"
+				emit(imageStyle.tag())
+				literal.WriteString("🖼 " + label + " (" + target + ")")
+				emit(current().tag())
+				i += 1 + n
+				continue
+			}
+
+			linkStyle := current()
+			linkStyle.color = "blue"
+			linkStyle.underline = true
+			emit(r.inline(label, linkStyle))
+			if target != "" && target != label {
+				targetStyle := current()
+				targetStyle.color = "gray"
+				emit(current().tag() + " " + targetStyle.tag())
+				literal.WriteString("(" + target + ")")
+			}
+			emit(current().tag())
+			i += n
+			continue
+
+		case c == '<':
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				break
+			}
+			url := rest[1:end]
+			if strings.ContainsAny(url, " \t<") || !(strings.Contains(url, "://") || strings.HasPrefix(url, "mailto:")) {
+				break
+			}
+			linkStyle := current()
+			linkStyle.color = "blue"
+			linkStyle.underline = true
+			emit(linkStyle.tag())
+			literal.WriteString(url)
+			emit(current().tag())
+			i += end + 1
+			continue
+
+		case c == '*' || c == '_' || (c == '~' && strings.HasPrefix(rest, "~~")):
+			delim := rest[:1]
+			flag := &italic
+			if c == '~' {
+				delim = "~~"
+				flag = &strike
+			} else if runLength(rest, c) >= 2 {
+				delim = rest[:2]
+				flag = &bold
+			}
+
+			prev, next := rune(' '), rune(' ')
+			if i > 0 {
+				prev = rune(text[i-1])
+			}
+			if i+len(delim) < len(text) {
+				next = rune(text[i+len(delim)])
+			}
+			// "_" は snake_case のように単語の途中にあるときは強調にしない
+			intraword := c == '_' && (isWordChar(prev) || (*flag && isWordChar(next)))
+
+			if *flag && !unicode.IsSpace(prev) && !intraword {
+				*flag = false
+				emit(current().tag())
+				i += len(delim)
+				continue
+			}
+			if !*flag && !unicode.IsSpace(next) && !intraword && strings.Contains(text[i+len(delim):], delim) {
+				*flag = true
+				emit(current().tag())
+				i += len(delim)
+				continue
+			}
+			literal.WriteString(delim)
+			i += len(delim)
+			continue
+		}
+
+		literal.WriteByte(c)
+		i++
+	}
+	out.WriteString(tview.Escape(literal.String()))
+	return out.String()
+}
+
+// parseLink は "[label](target)" を読み取る。読み取ったバイト数も返す
+func parseLink(text string) (label string, target string, n int, ok bool) {
+	if !strings.HasPrefix(text, "[") {
+		return "", "", 0, false
+	}
+
+	// 対応する "]" を探す。コードスパンの中やエスケープされた括弧は数えない
+	depth := 0
+	closing := -1
+	for i := 0; i < len(text) && closing < 0; i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '`':
+			if end := strings.IndexByte(text[i+1:], '`'); end >= 0 {
+				i += end + 1
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				closing = i
+			}
+		}
+	}
+	if closing < 0 || closing+1 >= len(text) || text[closing+1] != '(' {
+		return "", "", 0, false
+	}
+
+	depth = 0
+	for i := closing + 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				// タイトル（[label](url "title")）は表示しない
+				target := strings.TrimSpace(text[closing+2 : i])
+				if fields := strings.Fields(target); len(fields) > 0 {
+					target = fields[0]
+				}
+				target = strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">")
+				return text[1:closing], target, i + 1, true
+			}
+		}
+	}
+	return "", "", 0, false
+}
+
+// runLength は text の先頭に c が何文字続いているかを返す
+func runLength(text string, c byte) int {
+	n := 0
+	for n < len(text) && text[n] == c {
+		n++
+	}
+	return n
+}
+
+// isPunct はバックスラッシュでエスケープできる ASCII の記号かを返す
+func isPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isWordChar(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c)
+}
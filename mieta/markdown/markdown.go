@@ -0,0 +1,369 @@
+package markdown
+
+import (
+	"github.com/rivo/tview"
+	"regexp"
+	"strings"
+)
+
+var (
+	headingPattern   = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	setextPattern    = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	hrPattern        = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	fencePattern     = regexp.MustCompile("^([ \t]*)(`{3,}|~{3,})[ \t]*([^`]*)$")
+	listItemPattern  = regexp.MustCompile(`^([ \t]*)([-*+]|\d{1,9}[.)])(?:[ \t]+(.*))?$`)
+	tableDelimiter   = regexp.MustCompile(`^[ \t]*\|?[ \t]*:?-+:?[ \t]*(?:\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+	taskItemPattern  = regexp.MustCompile(`^\[([ xX])\][ \t]+`)
+	blockQuoteIndent = regexp.MustCompile(`^ {0,3}>`)
+)
+
+// リストの深さごとの記号
+var bullets = []string{"•", "◦", "▪"}
+
+// Render は Markdown を tview の色タグを使ったテキストにする
+// highlight はフェンスで囲まれたコードブロックを言語名でハイライトする関数。nil ならハイライトしない
+func Render(source string, highlight func(lang string, code string) string) string {
+	r := &renderer{highlight: highlight}
+	lines := strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n")
+	return strings.Join(r.renderBlocks(lines), "\n")
+}
+
+type renderer struct {
+	highlight func(lang string, code string) string
+}
+
+// renderBlocks は行ごとにブロックを判定して表示用の行にする
+func (r *renderer) renderBlocks(lines []string) []string {
+	var out []string
+	// リストの中では、字下げされた行をコードブロックではなく項目の続きとして扱う
+	inList := false
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			out = append(out, "")
+			i++
+			continue
+		case fencePattern.MatchString(line) && (inList || indentWidth(line) <= 3):
+			var code []string
+			code, i = r.renderFence(lines, i)
+			out = append(out, code...)
+			continue
+		case headingPattern.MatchString(line):
+			m := headingPattern.FindStringSubmatch(line)
+			out = append(out, r.renderHeading(len(m[1]), m[2]))
+			inList = false
+			i++
+			continue
+		case hrPattern.MatchString(line):
+			out = append(out, "[gray]"+strings.Repeat("─", 40)+"[-]")
+			inList = false
+			i++
+			continue
+		case blockQuoteIndent.MatchString(line):
+			var quoted []string
+			for i < len(lines) && blockQuoteIndent.MatchString(lines[i]) {
+				inner := strings.TrimPrefix(strings.TrimLeft(lines[i], " "), ">")
+				quoted = append(quoted, strings.TrimPrefix(inner, " "))
+				i++
+			}
+			for _, quotedLine := range r.renderBlocks(quoted) {
+				out = append(out, "[gray]│[-] "+quotedLine)
+			}
+			inList = false
+			continue
+		case listItemPattern.MatchString(line):
+			out = append(out, r.renderListItem(line))
+			inList = true
+			i++
+			continue
+		case isTableStart(lines, i):
+			var table []string
+			table, i = r.renderTable(lines, i)
+			out = append(out, table...)
+			inList = false
+			continue
+		case inList && indentWidth(line) > 0:
+			// リストの項目の続き
+			indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			out = append(out, indent+r.renderInline(trimmed, style{}))
+			i++
+			continue
+		case indentWidth(line) >= 4:
+			// 途中の空行はコードブロックに含めるが、最後の空行は含めない
+			end := i
+			for j := i; j < len(lines) && (indentWidth(lines[j]) >= 4 || strings.TrimSpace(lines[j]) == ""); j++ {
+				if strings.TrimSpace(lines[j]) != "" {
+					end = j + 1
+				}
+			}
+			for ; i < end; i++ {
+				code := strings.TrimPrefix(expandTabs(lines[i]), "    ")
+				out = append(out, "  [orange]"+tview.Escape(code)+"[-]")
+			}
+			continue
+		}
+
+		// 段落。次の行が "===" や "---" なら見出しにする
+		inList = false
+		var paragraph []string
+		for i < len(lines) && strings.TrimSpace(lines[i]) != "" {
+			if len(paragraph) > 0 && (setextPattern.MatchString(lines[i]) || r.startsBlock(lines, i)) {
+				break
+			}
+			paragraph = append(paragraph, lines[i])
+			i++
+		}
+		if i < len(lines) && setextPattern.MatchString(lines[i]) {
+			level := 2
+			if strings.HasPrefix(strings.TrimSpace(lines[i]), "=") {
+				level = 1
+			}
+			out = append(out, r.renderHeading(level, strings.Join(trimLines(paragraph), " ")))
+			i++
+			continue
+		}
+		for _, paragraphLine := range paragraph {
+			out = append(out, r.renderInline(trimLineBreak(paragraphLine), style{}))
+		}
+	}
+	return out
+}
+
+// startsBlock は lines[i] から段落以外のブロックが始まるかを返す
+func (r *renderer) startsBlock(lines []string, i int) bool {
+	line := lines[i]
+	return (fencePattern.MatchString(line) && indentWidth(line) <= 3) ||
+		headingPattern.MatchString(line) ||
+		hrPattern.MatchString(line) ||
+		blockQuoteIndent.MatchString(line) ||
+		listItemPattern.MatchString(line) ||
+		isTableStart(lines, i)
+}
+
+// isTableStart は lines[i] が表の見出しの行かを返す。次の行が区切りの行で、列の数が同じなら表とみなす
+func isTableStart(lines []string, i int) bool {
+	if i+1 >= len(lines) || !strings.Contains(lines[i], "|") || !tableDelimiter.MatchString(lines[i+1]) {
+		return false
+	}
+	return len(splitTableRow(lines[i])) == len(splitTableRow(lines[i+1]))
+}
+
+// renderHeading は見出しを表示用の行にする
+func (r *renderer) renderHeading(level int, text string) string {
+	switch level {
+	case 1:
+		return r.renderInline(text, style{color: "yellow", bold: true, underline: true})
+	case 2:
+		return r.renderInline(text, style{color: "yellow", bold: true})
+	default:
+		return r.renderInline(text, style{color: "green", bold: true})
+	}
+}
+
+// renderFence は lines[start] から始まるフェンスで囲まれたコードブロックを表示用の行にする
+// 閉じるフェンスの次の行の位置も返す。閉じていなければ最後まで読む
+func (r *renderer) renderFence(lines []string, start int) ([]string, int) {
+	m := fencePattern.FindStringSubmatch(lines[start])
+	indent, fence := m[1], m[2]
+	lang := ""
+	if fields := strings.Fields(m[3]); len(fields) > 0 {
+		lang = fields[0]
+	}
+
+	var code []string
+	i := start + 1
+	for ; i < len(lines); i++ {
+		closing := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(closing, fence) && strings.Trim(closing, fence[:1]) == "" {
+			i++
+			break
+		}
+		code = append(code, strings.TrimPrefix(lines[i], indent))
+	}
+
+	source := strings.Join(code, "\n")
+	var rendered string
+	if r.highlight != nil && lang != "" {
+		rendered = strings.TrimRight(r.highlight(lang, source+"\n"), "\n")
+	} else {
+		rendered = "[orange]" + tview.Escape(source) + "[-]"
+	}
+
+	out := []string{indent + "[gray]" + tview.Escape(fence+lang) + "[-]"}
+	for _, codeLine := range strings.Split(rendered, "\n") {
+		out = append(out, indent+"  "+codeLine)
+	}
+	out = append(out, indent+"[gray]"+tview.Escape(fence)+"[-]")
+	return out, i
+}
+
+// renderListItem はリストの項目を表示用の行にする。チェックボックスは記号にする
+func (r *renderer) renderListItem(line string) string {
+	m := listItemPattern.FindStringSubmatch(line)
+	indent, marker, content := m[1], m[2], m[3]
+
+	if strings.ContainsAny(marker[:1], "-*+") {
+		marker = bullets[(indentWidth(indent)/2)%len(bullets)]
+	}
+	if task := taskItemPattern.FindStringSubmatch(content); task != nil {
+		if task[1] == " " {
+			marker += " ☐"
+		} else {
+			marker += " [green]☑[-]"
+		}
+		content = content[len(task[0]):]
+	}
+	return indent + "[aqua]" + marker + "[-] " + r.renderInline(trimLineBreak(content), style{})
+}
+
+// renderTable は lines[start] から始まる表を、列の幅をそろえて表示用の行にする
+// 表の次の行の位置も返す
+func (r *renderer) renderTable(lines []string, start int) ([]string, int) {
+	header := splitTableRow(lines[start])
+	var aligns []string
+	for _, cell := range splitTableRow(lines[start+1]) {
+		switch {
+		case strings.HasPrefix(cell, ":") && strings.HasSuffix(cell, ":"):
+			aligns = append(aligns, "center")
+		case strings.HasSuffix(cell, ":"):
+			aligns = append(aligns, "right")
+		default:
+			aligns = append(aligns, "left")
+		}
+	}
+
+	// 1 行目は見出しなので太字にする
+	rows := [][]string{}
+	row := []string{}
+	for _, cell := range header {
+		row = append(row, r.renderInline(cell, style{bold: true}))
+	}
+	rows = append(rows, row)
+
+	i := start + 2
+	for ; i < len(lines) && strings.TrimSpace(lines[i]) != "" && strings.Contains(lines[i], "|"); i++ {
+		row := []string{}
+		for _, cell := range splitTableRow(lines[i]) {
+			row = append(row, r.renderInline(cell, style{}))
+		}
+		rows = append(rows, row)
+	}
+
+	columns := len(aligns)
+	widths := make([]int, columns)
+	for _, row := range rows {
+		for c := 0; c < columns && c < len(row); c++ {
+			widths[c] = max(widths[c], tview.TaggedStringWidth(row[c]))
+		}
+	}
+
+	separator := " [gray]│[-] "
+	var out []string
+	for n, row := range rows {
+		cells := make([]string, columns)
+		for c := range columns {
+			cell := ""
+			if c < len(row) {
+				cell = row[c]
+			}
+			cells[c] = pad(cell, widths[c], aligns[c])
+		}
+		out = append(out, strings.Join(cells, separator))
+
+		if n == 0 {
+			rules := make([]string, columns)
+			for c, width := range widths {
+				rules[c] = strings.Repeat("─", width)
+			}
+			out = append(out, "[gray]"+strings.Join(rules, "─┼─")+"[-]")
+		}
+	}
+	return out, i
+}
+
+// splitTableRow は表の行をセルに分ける。コードスパンの中やエスケープされた "|" では分けない
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+
+	var cells []string
+	var cell strings.Builder
+	inCode := false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case c == '`':
+			inCode = !inCode
+			cell.WriteByte(c)
+		case c == '|' && !inCode:
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(c)
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// pad は色タグを除いた幅が width になるように text に空白を足す
+func pad(text string, width int, align string) string {
+	space := width - tview.TaggedStringWidth(text)
+	if space <= 0 {
+		return text
+	}
+	switch align {
+	case "right":
+		return strings.Repeat(" ", space) + text
+	case "center":
+		return strings.Repeat(" ", space/2) + text + strings.Repeat(" ", space-space/2)
+	default:
+		return text + strings.Repeat(" ", space)
+	}
+}
+
+// indentWidth は行頭の空白の幅を返す。タブは 4 文字として数える
+func indentWidth(line string) int {
+	width := 0
+	for _, c := range line {
+		switch c {
+		case ' ':
+			width++
+		case '\t':
+			width += 4 - width%4
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+// expandTabs は行頭のタブを空白にする
+func expandTabs(line string) string {
+	rest := strings.TrimLeft(line, " \t")
+	return strings.Repeat(" ", indentWidth(line)) + rest
+}
+
+// trimLineBreak は行末の改行を表す空白やバックスラッシュを取り除く
+func trimLineBreak(line string) string {
+	line = strings.TrimSpace(line)
+	if strings.HasSuffix(line, `\`) && !strings.HasSuffix(line, `\\`) {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+func trimLines(lines []string) []string {
+	trimmed := make([]string, len(lines))
+	for i, line := range lines {
+		trimmed[i] = strings.TrimSpace(line)
+	}
+	return trimmed
+}
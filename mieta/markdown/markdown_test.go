@@ -0,0 +1,129 @@
+package markdown
+
+import (
+	"github.com/rivo/tview"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		// 見出しは深さで色と属性を変える
+		{"atx heading 1", "# Title", []string{"[yellow::bu]Title[-::-]"}},
+		{"atx heading 2 with closing", "## Sub ##", []string{"[yellow::b]Sub[-::-]"}},
+		{"atx heading 3", "### Deep", []string{"[green::b]Deep[-::-]"}},
+		{"setext heading 1", "Title\n===", []string{"[yellow::bu]Title[-::-]"}},
+		{"setext heading 2", "Title\n---", []string{"[yellow::b]Title[-::-]"}},
+		// リストの記号は深さで変え、チェックボックスは記号にする
+		{"nested list", "- a\n  - b\n    - c", []string{
+			"[aqua]•[-] a[-::-]",
+			"  [aqua]◦[-] b[-::-]",
+			"    [aqua]▪[-] c[-::-]",
+		}},
+		{"task list", "- [ ] todo\n- [x] done", []string{
+			"[aqua]• ☐[-] todo[-::-]",
+			"[aqua]• [green]☑[-][-] done[-::-]",
+		}},
+		{"ordered list", "1. one\n2) two", []string{
+			"[aqua]1.[-] one[-::-]",
+			"[aqua]2)[-] two[-::-]",
+		}},
+		{"list continuation", "- item\n  continued", []string{
+			"[aqua]•[-] item[-::-]",
+			"  continued[-::-]",
+		}},
+		// 引用の中もブロックとして表示する
+		{"blockquote", "> quote\n> - item", []string{
+			"[gray]│[-] quote[-::-]",
+			"[gray]│[-] [aqua]•[-] item[-::-]",
+		}},
+		{"horizontal rule", "text\n***\nafter", []string{
+			"text[-::-]",
+			"[gray]" + strings.Repeat("─", 40) + "[-]",
+			"after[-::-]",
+		}},
+		// 表は列の幅をそろえ、区切りの行の ":" で左、中央、右に寄せる
+		{"aligned table", "| L | C | R |\n|:--|:-:|--:|\n| a | bb | c |\n| long | x | yy |", []string{
+			"[-::b]L[-::-]    [gray]│[-] [-::b]C[-::-]  [gray]│[-]  [-::b]R[-::-]",
+			"[gray]─────┼────┼───[-]",
+			"a[-::-]    [gray]│[-] bb[-::-] [gray]│[-]  c[-::-]",
+			"long[-::-] [gray]│[-] x[-::-]  [gray]│[-] yy[-::-]",
+		}},
+		// コードブロックの中の "[" もエスケープする
+		{"fenced code", "```go\nfmt.Println(\"[x]\")\n```", []string{
+			"[gray]```go[-]",
+			"  [orange]fmt.Println(\"[x[]\")[-]",
+			"[gray]```[-]",
+		}},
+		{"tilde fence", "~~~\ncode\n~~~\nafter", []string{
+			"[gray]~~~[-]",
+			"  [orange]code[-]",
+			"[gray]~~~[-]",
+			"after[-::-]",
+		}},
+		// 閉じていないフェンスは最後までコードブロックにする
+		{"unclosed fence", "```\n# not a heading\nline2", []string{
+			"[gray]```[-]",
+			"  [orange]# not a heading",
+			"  line2[-]",
+			"[gray]```[-]",
+		}},
+		{"indented code", "    code [x]\n\n    more\n\nafter", []string{
+			"  [orange]code [x[][-]",
+			"  [orange][-]",
+			"  [orange]more[-]",
+			"",
+			"after[-::-]",
+		}},
+		// 色タグと間違えないように、文中の "[" はエスケープする
+		{"literal brackets", "see [x] and a[b]", []string{"see [x[] and a[b[][-::-]"}},
+	}
+
+	for _, tt := range tests {
+		got := Render(tt.source, nil)
+		if want := strings.Join(tt.want, "\n"); got != want {
+			t.Errorf("Render(%s) =\n%s\nwant\n%s", tt.name, got, want)
+		}
+	}
+}
+
+func TestRenderTableAlignment(t *testing.T) {
+	source := "| Name | Size | Note |\n|:-----|-----:|:----:|\n| a | 1 | **bold** |\n| longer name | 12345 | `x` |\n| 日本語 | 2 | |"
+	lines := strings.Split(Render(source, nil), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("Render(table) = %d lines, want 5", len(lines))
+	}
+
+	// 強調やコードスパン、全角文字を含んでも、色タグを除いた幅がすべての行でそろう
+	width := tview.TaggedStringWidth(lines[0])
+	for _, line := range lines[1:] {
+		if got := tview.TaggedStringWidth(line); got != width {
+			t.Errorf("width of %q = %d, want %d", line, got, width)
+		}
+	}
+}
+
+func TestRenderHighlight(t *testing.T) {
+	highlight := func(lang string, code string) string {
+		return "<" + lang + ">" + code + "</" + lang + ">\n"
+	}
+
+	got := Render("```go title=x\nfmt.Println()\n```\n```\nplain\n```", highlight)
+	want := strings.Join([]string{
+		"[gray]```go[-]",
+		"  <go>fmt.Println()",
+		"  </go>",
+		"[gray]```[-]",
+		// 言語名がなければハイライトしない
+		"[gray]```[-]",
+		"  [orange]plain[-]",
+		"[gray]```[-]",
+	}, "\n")
+	if got != want {
+		t.Errorf("Render with highlight =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,18 @@
+package search_view
+
+import "github.com/tokuhirom/mieta/mieta/session"
+
+// SaveSession は検索の設定と最後のクエリを s に書き込む
+func (s *SearchView) SaveSession(sess *session.Session) {
+	sess.SearchQuery = s.InputField.GetText()
+	sess.UseRegex = s.UseRegex
+	sess.IgnoreCase = s.IgnoreCase
+}
+
+// RestoreSession は検索の設定と最後のクエリを復元する
+func (s *SearchView) RestoreSession(sess *session.Session) {
+	s.InputField.SetText(sess.SearchQuery)
+	s.UseRegex = sess.UseRegex
+	s.IgnoreCase = sess.IgnoreCase
+	s.updateStatusBar()
+}
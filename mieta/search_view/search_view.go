@@ -17,7 +17,6 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
-	"unicode/utf8"
 )
 
 // SearchResult represents a search result or error message
@@ -427,17 +426,25 @@ func (s *SearchView) loadFileContent(path string, lineNumber int) {
 	s.ContentView.Clear()
 	s.ContentView.SetTitle(path)
 
-	content, err := os.ReadFile(path)
+	raw, err := os.ReadFile(path)
 	if err != nil {
 		s.ContentView.SetText(fmt.Sprintf("[red]Error opening file: %v", err))
 		return
 	}
 
-	// Check if content is valid UTF-8
-	if !utf8.Valid(content) {
+	// 文字コードを判定して UTF-8 に変換する
+	relPath, _ := filepath.Rel(s.RootDir, path)
+	charset := files_view.DetectCharset(s.Config, filepath.ToSlash(relPath), raw)
+	if charset == nil {
 		s.ContentView.SetText("[red]Binary file")
 		return
 	}
+	content, err := charset.Decode(raw)
+	if err != nil {
+		s.ContentView.SetText(fmt.Sprintf("[red]Failed to decode as %s: %v", charset, err))
+		return
+	}
+	s.ContentView.SetTitle(fmt.Sprintf("%s (%s)", path, charset))
 
 	// Check file size limit for highlighting
 	highlightLimit := s.Config.HighlightLimit
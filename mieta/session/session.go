@@ -0,0 +1,76 @@
+package session
+
+import (
+	"github.com/tokuhirom/mieta/mieta/state"
+	"log"
+	"sort"
+	"time"
+)
+
+// fileName はセッションを保存するファイルの名前
+const fileName = "sessions.json"
+
+// maxSessions は保存しておくセッションの数。古いものから捨てる
+const maxSessions = 100
+
+// Session は終了時の画面の状態。ルートディレクトリごとに保存する
+type Session struct {
+	// 展開していたディレクトリ
+	ExpandedDirs []string `json:"expanded_dirs"`
+	// 選択していたノードのパス
+	SelectedPath string `json:"selected_path"`
+	// プレビューのスクロール位置
+	PreviewScroll int `json:"preview_scroll"`
+	// 左ペインの幅
+	TreeWidth int `json:"tree_width"`
+
+	// 検索の設定
+	SearchQuery string `json:"search_query"`
+	UseRegex    bool   `json:"use_regex"`
+	IgnoreCase  bool   `json:"ignore_case"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Load は rootDir のセッションを返す。保存されていなければ nil を返す
+func Load(rootDir string) (*Session, error) {
+	sessions := map[string]*Session{}
+	if err := state.Load(fileName, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions[rootDir], nil
+}
+
+// Save は rootDir のセッションを保存する
+func Save(rootDir string, session *Session) error {
+	sessions := map[string]*Session{}
+	if err := state.Load(fileName, &sessions); err != nil {
+		// 壊れている場合は作り直す
+		log.Printf("Discarding saved sessions: %v", err)
+		sessions = map[string]*Session{}
+	}
+
+	session.UpdatedAt = time.Now()
+	sessions[rootDir] = session
+	prune(sessions)
+
+	return state.Save(fileName, sessions)
+}
+
+// prune は maxSessions を超えた分のセッションを古い順に削除する
+func prune(sessions map[string]*Session) {
+	if len(sessions) <= maxSessions {
+		return
+	}
+
+	rootDirs := make([]string, 0, len(sessions))
+	for rootDir := range sessions {
+		rootDirs = append(rootDirs, rootDir)
+	}
+	sort.Slice(rootDirs, func(i, j int) bool {
+		return sessions[rootDirs[i]].UpdatedAt.After(sessions[rootDirs[j]].UpdatedAt)
+	})
+	for _, rootDir := range rootDirs[maxSessions:] {
+		delete(sessions, rootDir)
+	}
+}
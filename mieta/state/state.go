@@ -0,0 +1,77 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Dir は mieta の状態を保存するディレクトリを返す
+// $XDG_STATE_HOME/mieta、未設定なら ~/.local/state/mieta を使う
+func Dir() (string, error) {
+	if stateHome := os.Getenv("XDG_STATE_HOME"); stateHome != "" {
+		return filepath.Join(stateHome, "mieta"), nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".local", "state", "mieta"), nil
+}
+
+// Load は状態ディレクトリの name から JSON を読み込んで v に格納する
+// ファイルがまだない場合は何もせずに nil を返す
+func Load(name string, v any) error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(content, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
+
+// Save は v を JSON にして状態ディレクトリの name に保存する
+// 書き込み途中で壊れないように、一時ファイルに書いてから置き換える
+func Save(name string, v any) error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp(dir, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(content); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), filepath.Join(dir, name))
+}